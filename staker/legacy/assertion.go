@@ -4,6 +4,8 @@
 package legacystaker
 
 import (
+	"encoding/json"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -91,3 +93,170 @@ func (n *NodeInfo) GlobalStates() [2]rollup_legacy_gen.GlobalState {
 		n.Assertion.AfterState.GlobalState.AsLegacySolidityStruct(),
 	}
 }
+
+// The JSON shapes below are kept separate from the Go types so the encoding stays stable if those are changed.
+
+type globalStateJSON struct {
+	BlockHash  common.Hash `json:"blockHash"`
+	SendRoot   common.Hash `json:"sendRoot"`
+	Batch      uint64      `json:"batch"`
+	PosInBatch uint64      `json:"posInBatch"`
+}
+
+type executionStateJSON struct {
+	GlobalState   globalStateJSON `json:"globalState"`
+	MachineStatus uint8           `json:"machineStatus"`
+}
+
+type assertionJSON struct {
+	BeforeState *executionStateJSON `json:"beforeState"`
+	AfterState  *executionStateJSON `json:"afterState"`
+	NumBlocks   uint64              `json:"numBlocks"`
+}
+
+type nodeInfoJSON struct {
+	NodeNum                  uint64         `json:"nodeNum"`
+	L1BlockProposed          uint64         `json:"l1BlockProposed"`
+	ParentChainBlockProposed uint64         `json:"parentChainBlockProposed"`
+	Assertion                *assertionJSON `json:"assertion"`
+	InboxMaxCount            *big.Int       `json:"inboxMaxCount"`
+	AfterInboxBatchAcc       common.Hash    `json:"afterInboxBatchAcc"`
+	NodeHash                 common.Hash    `json:"nodeHash"`
+	WasmModuleRoot           common.Hash    `json:"wasmModuleRoot"`
+}
+
+func executionStateToJSON(s *validator.ExecutionState) *executionStateJSON {
+	if s == nil {
+		return nil
+	}
+	return &executionStateJSON{
+		GlobalState: globalStateJSON{
+			BlockHash:  s.GlobalState.BlockHash,
+			SendRoot:   s.GlobalState.SendRoot,
+			Batch:      s.GlobalState.Batch,
+			PosInBatch: s.GlobalState.PosInBatch,
+		},
+		MachineStatus: uint8(s.MachineStatus),
+	}
+}
+
+func executionStateFromJSON(s *executionStateJSON) *validator.ExecutionState {
+	if s == nil {
+		return nil
+	}
+	return &validator.ExecutionState{
+		GlobalState: validator.GoGlobalState{
+			BlockHash:  s.GlobalState.BlockHash,
+			SendRoot:   s.GlobalState.SendRoot,
+			Batch:      s.GlobalState.Batch,
+			PosInBatch: s.GlobalState.PosInBatch,
+		},
+		MachineStatus: validator.MachineStatus(s.MachineStatus),
+	}
+}
+
+// MarshalJSON encodes the node with its hashes as 0x-prefixed hex, for dumping node state to logs or files.
+func (n *NodeInfo) MarshalJSON() ([]byte, error) {
+	enc := nodeInfoJSON{
+		NodeNum:                  n.NodeNum,
+		L1BlockProposed:          n.L1BlockProposed,
+		ParentChainBlockProposed: n.ParentChainBlockProposed,
+		InboxMaxCount:            n.InboxMaxCount,
+		AfterInboxBatchAcc:       n.AfterInboxBatchAcc,
+		NodeHash:                 n.NodeHash,
+		WasmModuleRoot:           n.WasmModuleRoot,
+	}
+	if n.Assertion != nil {
+		enc.Assertion = &assertionJSON{
+			BeforeState: executionStateToJSON(n.Assertion.BeforeState),
+			AfterState:  executionStateToJSON(n.Assertion.AfterState),
+			NumBlocks:   n.Assertion.NumBlocks,
+		}
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a node encoded by MarshalJSON.
+func (n *NodeInfo) UnmarshalJSON(data []byte) error {
+	var dec nodeInfoJSON
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	*n = NodeInfo{
+		NodeNum:                  dec.NodeNum,
+		L1BlockProposed:          dec.L1BlockProposed,
+		ParentChainBlockProposed: dec.ParentChainBlockProposed,
+		InboxMaxCount:            dec.InboxMaxCount,
+		AfterInboxBatchAcc:       dec.AfterInboxBatchAcc,
+		NodeHash:                 dec.NodeHash,
+		WasmModuleRoot:           dec.WasmModuleRoot,
+	}
+	if dec.Assertion != nil {
+		n.Assertion = &Assertion{
+			BeforeState: executionStateFromJSON(dec.Assertion.BeforeState),
+			AfterState:  executionStateFromJSON(dec.Assertion.AfterState),
+			NumBlocks:   dec.Assertion.NumBlocks,
+		}
+	}
+	return nil
+}
+
+// FieldDiff is a field that differs between two nodes, with its value in each.
+type FieldDiff struct {
+	Field string
+	A     string
+	B     string
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Field, d.A, d.B)
+}
+
+func formatBig(x *big.Int) string {
+	if x == nil {
+		return "<nil>"
+	}
+	return x.String()
+}
+
+func formatExecutionState(s *validator.ExecutionState) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%v, MachineStatus: %d", s.GlobalState, s.MachineStatus)
+}
+
+// DiffNodeInfo returns the fields that differ between a and b, in declaration order, for triaging reorgs and
+// consistency bugs. A nil node is compared as if all its fields were zero.
+func DiffNodeInfo(a, b *NodeInfo) []FieldDiff {
+	if a == nil {
+		a = &NodeInfo{}
+	}
+	if b == nil {
+		b = &NodeInfo{}
+	}
+	var diffs []FieldDiff
+	add := func(field string, valueA, valueB string) {
+		if valueA != valueB {
+			diffs = append(diffs, FieldDiff{Field: field, A: valueA, B: valueB})
+		}
+	}
+	add("NodeNum", fmt.Sprint(a.NodeNum), fmt.Sprint(b.NodeNum))
+	add("L1BlockProposed", fmt.Sprint(a.L1BlockProposed), fmt.Sprint(b.L1BlockProposed))
+	add("ParentChainBlockProposed", fmt.Sprint(a.ParentChainBlockProposed), fmt.Sprint(b.ParentChainBlockProposed))
+	assertionA, assertionB := a.Assertion, b.Assertion
+	if assertionA == nil {
+		assertionA = &Assertion{}
+	}
+	if assertionB == nil {
+		assertionB = &Assertion{}
+	}
+	add("Assertion.BeforeState", formatExecutionState(assertionA.BeforeState), formatExecutionState(assertionB.BeforeState))
+	add("Assertion.AfterState", formatExecutionState(assertionA.AfterState), formatExecutionState(assertionB.AfterState))
+	add("Assertion.NumBlocks", fmt.Sprint(assertionA.NumBlocks), fmt.Sprint(assertionB.NumBlocks))
+	add("InboxMaxCount", formatBig(a.InboxMaxCount), formatBig(b.InboxMaxCount))
+	add("AfterInboxBatchAcc", a.AfterInboxBatchAcc.Hex(), b.AfterInboxBatchAcc.Hex())
+	add("NodeHash", a.NodeHash.Hex(), b.NodeHash.Hex())
+	add("WasmModuleRoot", a.WasmModuleRoot.Hex(), b.WasmModuleRoot.Hex())
+	return diffs
+}
@@ -0,0 +1,132 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/validator"
+)
+
+func TestNodeInfoJSONRoundTrip(t *testing.T) {
+	info := &NodeInfo{
+		NodeNum:                  42,
+		L1BlockProposed:          1000,
+		ParentChainBlockProposed: 2000,
+		Assertion: &Assertion{
+			BeforeState: &validator.ExecutionState{
+				GlobalState: validator.GoGlobalState{
+					BlockHash:  common.HexToHash("0x01"),
+					SendRoot:   common.HexToHash("0x02"),
+					Batch:      3,
+					PosInBatch: 4,
+				},
+				MachineStatus: validator.MachineStatusFinished,
+			},
+			AfterState: &validator.ExecutionState{
+				GlobalState: validator.GoGlobalState{
+					BlockHash:  common.HexToHash("0x05"),
+					SendRoot:   common.HexToHash("0x06"),
+					Batch:      7,
+					PosInBatch: 0,
+				},
+				MachineStatus: validator.MachineStatusErrored,
+			},
+			NumBlocks: 8,
+		},
+		InboxMaxCount:      new(big.Int).Lsh(big.NewInt(1), 100),
+		AfterInboxBatchAcc: common.HexToHash("0x09"),
+		NodeHash:           common.HexToHash("0x0a"),
+		WasmModuleRoot:     common.HexToHash("0x0b"),
+	}
+	data, err := json.Marshal(info)
+	Require(t, err)
+	if !strings.Contains(string(data), `"nodeHash":"`+info.NodeHash.Hex()+`"`) {
+		Fail(t, "expected node hash rendered as hex, got", string(data))
+	}
+
+	var decoded NodeInfo
+	Require(t, json.Unmarshal(data, &decoded))
+	if decoded.InboxMaxCount.Cmp(info.InboxMaxCount) != 0 {
+		Fail(t, "inbox max count changed in round trip from", info.InboxMaxCount, "to", decoded.InboxMaxCount)
+	}
+	reencoded, err := json.Marshal(&decoded)
+	Require(t, err)
+	if string(reencoded) != string(data) {
+		Fail(t, "expected a stable encoding, got", string(data), "and", string(reencoded))
+	}
+	expected, actual := *info, decoded
+	expected.InboxMaxCount, actual.InboxMaxCount = nil, nil
+	if !reflect.DeepEqual(expected, actual) {
+		Fail(t, "node info changed in round trip from", expected, "to", actual)
+	}
+}
+
+func TestDiffNodeInfo(t *testing.T) {
+	node := func() *NodeInfo {
+		return &NodeInfo{
+			NodeNum: 1,
+			Assertion: &Assertion{
+				BeforeState: &validator.ExecutionState{MachineStatus: validator.MachineStatusFinished},
+				AfterState:  &validator.ExecutionState{MachineStatus: validator.MachineStatusFinished},
+				NumBlocks:   2,
+			},
+			InboxMaxCount: big.NewInt(3),
+			NodeHash:      common.HexToHash("0x04"),
+		}
+	}
+	withoutAssertion := node()
+	withoutAssertion.Assertion = nil
+	withoutInboxMaxCount := node()
+	withoutInboxMaxCount.InboxMaxCount = nil
+	allDifferent := &NodeInfo{
+		NodeNum:                  5,
+		L1BlockProposed:          6,
+		ParentChainBlockProposed: 7,
+		Assertion: &Assertion{
+			BeforeState: &validator.ExecutionState{MachineStatus: validator.MachineStatusErrored},
+			AfterState:  &validator.ExecutionState{MachineStatus: validator.MachineStatusErrored},
+			NumBlocks:   8,
+		},
+		InboxMaxCount:      big.NewInt(9),
+		AfterInboxBatchAcc: common.HexToHash("0x0a"),
+		NodeHash:           common.HexToHash("0x0b"),
+		WasmModuleRoot:     common.HexToHash("0x0c"),
+	}
+	for _, tc := range []struct {
+		name   string
+		a, b   *NodeInfo
+		fields []string
+	}{
+		{"both nil", nil, nil, nil},
+		{"nil and zero", nil, &NodeInfo{}, nil},
+		{"identical", node(), node(), nil},
+		{"nil and non-nil", nil, node(), []string{"NodeNum", "Assertion.BeforeState", "Assertion.AfterState", "Assertion.NumBlocks", "InboxMaxCount", "NodeHash"}},
+		{"nil assertion", node(), withoutAssertion, []string{"Assertion.BeforeState", "Assertion.AfterState", "Assertion.NumBlocks"}},
+		{"nil inbox max count", withoutInboxMaxCount, node(), []string{"InboxMaxCount"}},
+		{"declaration order", node(), allDifferent, []string{
+			"NodeNum", "L1BlockProposed", "ParentChainBlockProposed", "Assertion.BeforeState", "Assertion.AfterState",
+			"Assertion.NumBlocks", "InboxMaxCount", "AfterInboxBatchAcc", "NodeHash", "WasmModuleRoot",
+		}},
+	} {
+		diffs := DiffNodeInfo(tc.a, tc.b)
+		var fields []string
+		for _, diff := range diffs {
+			fields = append(fields, diff.Field)
+		}
+		if !reflect.DeepEqual(fields, tc.fields) {
+			Fail(t, tc.name, "expected differing fields", tc.fields, "got", fields)
+		}
+	}
+	diffs := DiffNodeInfo(withoutInboxMaxCount, node())
+	if len(diffs) != 1 || diffs[0].A != "<nil>" || diffs[0].B != "3" {
+		Fail(t, "unexpected inbox max count diff", diffs)
+	}
+}
@@ -11,33 +11,95 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/solgen/go/challenge_legacy_gen"
 	"github.com/offchainlabs/nitro/solgen/go/rollup_legacy_gen"
+	"github.com/offchainlabs/nitro/util/containers"
 	"github.com/offchainlabs/nitro/util/headerreader"
 )
 
+var (
+	nodeCreationCacheHitCounter  = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/nodecreation/cache/hit", nil)
+	nodeCreationCacheMissCounter = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/nodecreation/cache/miss", nil)
+	l1BlockCacheHitCounter       = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/l1block/cache/hit", nil)
+	l1BlockCacheMissCounter      = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/l1block/cache/miss", nil)
+	nodeInfoCacheHitCounter      = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/nodeinfo/cache/hit", nil)
+	nodeInfoCacheMissCounter     = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/nodeinfo/cache/miss", nil)
+)
+
+// DefaultNodeCreationCacheSize is the number of node creation blocks cached by default.
+const DefaultNodeCreationCacheSize = 1024
+
+// DefaultL1BlockCacheSize is the number of parent chain to L1 block number mappings cached by default.
+const DefaultL1BlockCacheSize = 4096
+
+// DefaultNodeInfoCacheTTL is how long LookupNode results for unconfirmed nodes are cached for by default,
+// once the NodeInfo cache is enabled with WithNodeInfoCache.
+const DefaultNodeInfoCacheTTL = 30 * time.Second
+
+// DefaultChildrenLookupConcurrency bounds how many eth_getLogs segments LookupNodeChildren fetches at once by default.
+const DefaultChildrenLookupConcurrency = 4
+
+// DefaultNodeLookupConcurrency bounds how many nodes LookupNodes fetches at once by default.
+const DefaultNodeLookupConcurrency = 8
+
+// DefaultStakerLookupConcurrency bounds how many stakers StakerInfos fetches at once by default.
+const DefaultStakerLookupConcurrency = 8
+
+// ErrClosed is returned by RollupWatcher methods called after Close.
+var ErrClosed = errors.New("rollup watcher closed")
+
+// errInitializeTimeout is the cause of Initialize's context being cancelled by its own timeout.
+var errInitializeTimeout = errors.New("initialize timeout")
+
+// ErrTooManyChildren is matched by the *TooManyChildrenError LookupNodeChildren returns
+// when a node has more children than the configured maximum.
+var ErrTooManyChildren = errors.New("too many node children")
+
+// ErrNodeNotFinal is returned by LookupNode for a node created after the block of the watcher's finality tag.
+var ErrNodeNotFinal = errors.New("node not yet final")
+
+// ErrReorgDetected is returned when data read from the parent chain belongs to a block that is no longer canonical.
+// Callers can retry the lookup once the parent chain settles.
+var ErrReorgDetected = errors.New("parent chain reorg detected")
+
 var rollupInitializedID common.Hash
 var nodeCreatedID common.Hash
 var challengeCreatedID common.Hash
+var nodeConfirmedID common.Hash
+var nodeRejectedID common.Hash
+
+// rollupABI is the parsed rollup ABI the event IDs above come from.
+var rollupABI *abi.ABI
 
 func init() {
 	parsedRollup, err := rollup_legacy_gen.RollupUserLogicMetaData.GetAbi()
 	if err != nil {
 		panic(err)
 	}
+	rollupABI = parsedRollup
 	rollupInitializedID = parsedRollup.Events["RollupInitialized"].ID
 	nodeCreatedID = parsedRollup.Events["NodeCreated"].ID
 	challengeCreatedID = parsedRollup.Events["RollupChallengeStarted"].ID
+	nodeConfirmedID = parsedRollup.Events["NodeConfirmed"].ID
+	nodeRejectedID = parsedRollup.Events["NodeRejected"].ID
 }
 
 type StakerInfo struct {
@@ -47,14 +109,233 @@ type StakerInfo struct {
 	CurrentChallenge *uint64
 }
 
+// NodeState is the on-chain state of a node, as stored by the rollup contract.
+type NodeState struct {
+	// StateHash commits to the node's assertion and inbox count.
+	StateHash common.Hash
+	// ChallengeHash commits to the data a challenge of the node would be over.
+	ChallengeHash common.Hash
+	// ConfirmData is what confirming the node posts to the outbox: its block hash and send root.
+	ConfirmData common.Hash
+	// PrevNum is the node's parent.
+	PrevNum uint64
+	// DeadlineBlock is the parent chain block after which the node can be confirmed or rejected.
+	DeadlineBlock uint64
+	// NoChildConfirmedBeforeBlock is the block before which none of the node's children can be confirmed.
+	NoChildConfirmedBeforeBlock uint64
+	// StakerCount is how many stakers are staked on the node, including those staked on its descendants.
+	StakerCount uint64
+	// ChildStakerCount is how many stakers are staked on the node's children.
+	ChildStakerCount uint64
+	// FirstChildBlock is the parent chain block the node's first child was created in, or zero without children.
+	FirstChildBlock uint64
+	// LatestChildNumber is the node's most recently created child, or zero without children.
+	LatestChildNumber uint64
+	// CreatedAtBlock is the block the node was created in, as reported by the rollup.
+	// On L3s this is the L1 block number rather than the parent chain's.
+	CreatedAtBlock uint64
+	NodeHash       common.Hash
+}
+
 type RollupWatcher struct {
 	*rollup_legacy_gen.RollupUserLogic
 	address             common.Address
 	fromBlock           *big.Int
 	client              RollupWatcherL1Interface
+	fallbackClients     []RollupWatcherL1Interface
 	baseCallOpts        bind.CallOpts
 	unSupportedL3Method atomic.Bool
 	supportedL3Method   atomic.Bool
+	closed              atomic.Bool
+	closeCtx            context.Context
+	closeFunc           context.CancelCauseFunc
+
+	// l3MethodSupportFromStore is set while the L3 method support state was loaded and hasn't been observed since.
+	l3MethodSupportFromStore atomic.Bool
+	l3MethodSupportStore     L3MethodSupportStore
+
+	subscriptionsMutex sync.Mutex
+	subscriptions      []event.Subscription
+
+	nodeCreationCacheSize     int
+	l1BlockCacheSize          int
+	nodeInfoCacheSize         int
+	nodeInfoCacheTTL          time.Duration
+	nodeLookupConcurrency     int
+	stakerLookupConcurrency   int
+	childrenLookupConcurrency int
+	retryPolicy               RetryPolicy
+	callTimeout               time.Duration
+	initializeTimeout         time.Duration
+	defaultLogQueryRangeSize  uint64
+	childrenProgressFunc      ProgressFunc
+	maxChildren               uint64
+	finalityTag               rpc.BlockNumber
+	checkSegmentBoundaries    bool
+	nodeHashFunc              NodeHashFunc
+
+	creation            atomic.Pointer[rollup_legacy_gen.RollupUserLogicRollupInitialized]
+	challengeManagerCon atomic.Pointer[challenge_legacy_gen.ChallengeManager]
+
+	// latestConfirmedSeen is the latest confirmed node last read from the rollup;
+	// nodes up to it are resolved, so their cached NodeInfo never expires.
+	latestConfirmedSeen atomic.Uint64
+
+	cacheMutex        sync.Mutex // protects nodeCreationCache, l1BlockCache and nodeInfoCache
+	nodeCreationCache *containers.LruCache[uint64, *big.Int]
+	l1BlockCache      *containers.LruCache[uint64, uint64]
+	nodeInfoCache     *containers.LruCache[uint64, nodeInfoCacheEntry]
+}
+
+type RollupWatcherOption func(*RollupWatcher)
+
+// WithNodeCreationCacheSize sets how many node creation blocks are kept in memory.
+// A zero or negative size disables the cache.
+func WithNodeCreationCacheSize(size int) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.nodeCreationCacheSize = size
+	}
+}
+
+// WithL1BlockCacheSize sets how many parent chain to L1 block number mappings are kept in memory.
+// A zero or negative size disables the cache.
+func WithL1BlockCacheSize(size int) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.l1BlockCacheSize = size
+	}
+}
+
+// WithNodeInfoCache caches up to size LookupNode results. Confirmed (or otherwise resolved) nodes never change,
+// so they're kept until evicted, while newer nodes, which a reorg could still change, expire after ttl.
+// The cache is disabled by default; a zero or negative size keeps it disabled.
+func WithNodeInfoCache(size int, ttl time.Duration) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.nodeInfoCacheSize = size
+		r.nodeInfoCacheTTL = ttl
+	}
+}
+
+// WithChildrenLookupConcurrency sets how many eth_getLogs segments LookupNodeChildren and other range scans fetch
+// at once. Segment logs are reassembled in block order, so the resulting node hashes are unaffected.
+// Setting it to 1 fetches segments one after another, for providers that rate limit parallel eth_getLogs.
+// A zero or negative value removes the limit.
+func WithChildrenLookupConcurrency(concurrency int) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.childrenLookupConcurrency = concurrency
+	}
+}
+
+// WithNodeLookupConcurrency sets how many nodes LookupNodes fetches at once.
+// A zero or negative value removes the limit.
+func WithNodeLookupConcurrency(concurrency int) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.nodeLookupConcurrency = concurrency
+	}
+}
+
+// WithStakerLookupConcurrency sets how many stakers StakerInfos fetches at once,
+// which is also the batch size IterateStakers pages through stakers with.
+// A zero or negative value removes the limit.
+func WithStakerLookupConcurrency(concurrency int) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.stakerLookupConcurrency = concurrency
+	}
+}
+
+// WithCallTimeout bounds each individual eth_call made by the watcher.
+// The timeout is derived from the caller's context, so cancelling that still takes effect.
+// A zero duration (the default) leaves calls bounded only by the caller's context.
+func WithCallTimeout(timeout time.Duration) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.callTimeout = timeout
+	}
+}
+
+// WithInitializeTimeout bounds Initialize as a whole, which may take several calls to find the rollup's creation block.
+// The timeout is derived from the caller's context, so cancelling that still takes effect.
+// A zero duration (the default) leaves Initialize bounded only by the caller's context.
+func WithInitializeTimeout(timeout time.Duration) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.initializeTimeout = timeout
+	}
+}
+
+// WithDefaultLogQueryRangeSize sets the eth_getLogs block range used by multi-block log queries
+// whose caller doesn't specify one. Zero (the default) queries the whole range at once.
+func WithDefaultLogQueryRangeSize(rangeSize uint64) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.defaultLogQueryRangeSize = rangeSize
+	}
+}
+
+// WithChildrenProgressFunc sets a callback LookupNodeChildren reports its progress to after each log query segment,
+// e.g. to log how far a long scan has got. It's called from the scanning goroutines, so it should return quickly.
+func WithChildrenProgressFunc(progress ProgressFunc) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.childrenProgressFunc = progress
+	}
+}
+
+// WithMaxChildren limits how many children LookupNodeChildren collects before giving up with a *TooManyChildrenError,
+// protecting against rollups with enormous numbers of children. Zero (the default) means unlimited.
+func WithMaxChildren(maxChildren uint64) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.maxChildren = maxChildren
+	}
+}
+
+// WithFinalityTag makes LookupNode and the children lookups only return nodes created at or before the parent chain
+// block with the given tag, e.g. rpc.FinalizedBlockNumber or rpc.SafeBlockNumber, so that callers never see nodes
+// that could still be reorged out. LookupNode fails with ErrNodeNotFinal for later nodes, and children created
+// later are left out until they're final. rpc.LatestBlockNumber (the default) disables this for lower latency.
+func WithFinalityTag(tag rpc.BlockNumber) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.finalityTag = tag
+	}
+}
+
+// WithSegmentBoundaryChecks re-queries the blocks at each boundary between the eth_getLogs segments of a range scan,
+// logging an error if the segments missed any of their logs. It's a safety net for the range splitting arithmetic,
+// meant for tests and debugging as it costs two extra queries per boundary. It's disabled by default.
+func WithSegmentBoundaryChecks(enabled bool) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.checkSegmentBoundaries = enabled
+	}
+}
+
+// NodeHashFunc hashes the concatenation of data, like crypto.Keccak256Hash.
+type NodeHashFunc func(data ...[]byte) common.Hash
+
+// WithNodeHashFunc sets the hash function node hashes are chained with when looking up a node's children,
+// for rollup variants hashing differently, or tests checking the chaining with a deterministic hash.
+// Defaults to crypto.Keccak256Hash, as used by the rollup contract.
+func WithNodeHashFunc(hash NodeHashFunc) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.nodeHashFunc = hash
+	}
+}
+
+// WithRetryPolicy sets how log queries that fail with a transient error are retried.
+func WithRetryPolicy(policy RetryPolicy) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.retryPolicy = policy
+	}
+}
+
+// L3MethodSupportStore persists whether the rollup supports getNodeCreationBlockForLogLookup,
+// so that a restarted watcher doesn't need to probe it with a possibly reverting call.
+type L3MethodSupportStore interface {
+	// LoadL3MethodSupport returns the stored support state, with found false if none was stored yet.
+	LoadL3MethodSupport() (supported bool, found bool, err error)
+	StoreL3MethodSupport(supported bool) error
+}
+
+// WithL3MethodSupportStore loads the L3 method support state from store on creation and saves it whenever it's detected.
+// The stored state is re-probed if a later lookup disagrees with it.
+func WithL3MethodSupportStore(store L3MethodSupportStore) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.l3MethodSupportStore = store
+	}
 }
 
 type RollupWatcherL1Interface interface {
@@ -63,28 +344,137 @@ type RollupWatcherL1Interface interface {
 	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
 }
 
-func NewRollupWatcher(address common.Address, client RollupWatcherL1Interface, callOpts bind.CallOpts) (*RollupWatcher, error) {
-	con, err := rollup_legacy_gen.NewRollupUserLogic(address, client)
+func NewRollupWatcher(address common.Address, client RollupWatcherL1Interface, callOpts bind.CallOpts, opts ...RollupWatcherOption) (*RollupWatcher, error) {
+	r := &RollupWatcher{
+		address:                   address,
+		client:                    client,
+		baseCallOpts:              callOpts,
+		nodeCreationCacheSize:     DefaultNodeCreationCacheSize,
+		l1BlockCacheSize:          DefaultL1BlockCacheSize,
+		nodeInfoCacheTTL:          DefaultNodeInfoCacheTTL,
+		nodeLookupConcurrency:     DefaultNodeLookupConcurrency,
+		stakerLookupConcurrency:   DefaultStakerLookupConcurrency,
+		childrenLookupConcurrency: DefaultChildrenLookupConcurrency,
+		retryPolicy:               DefaultRetryPolicy,
+		finalityTag:               rpc.LatestBlockNumber,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if len(r.fallbackClients) > 0 {
+		r.client = newFailoverClient(append([]RollupWatcherL1Interface{client}, r.fallbackClients...))
+	}
+	con, err := rollup_legacy_gen.NewRollupUserLogic(address, &retryingClient{RollupWatcherL1Interface: r.client, policy: r.retryPolicy})
 	if err != nil {
 		return nil, err
 	}
+	r.RollupUserLogic = con
+	r.loadL3MethodSupport()
+	r.closeCtx, r.closeFunc = context.WithCancelCause(context.Background())
+	r.nodeCreationCache = containers.NewLruCache[uint64, *big.Int](r.nodeCreationCacheSize)
+	r.l1BlockCache = containers.NewLruCache[uint64, uint64](r.l1BlockCacheSize)
+	r.nodeInfoCache = containers.NewLruCache[uint64, nodeInfoCacheEntry](r.nodeInfoCacheSize)
+	return r, nil
+}
 
-	return &RollupWatcher{
-		address:         address,
-		client:          client,
-		baseCallOpts:    callOpts,
-		RollupUserLogic: con,
+// scopeContext returns a context derived from ctx that is also cancelled when the watcher is closed,
+// or an error wrapping ErrClosed if it already is. The returned cancel func must be called when done.
+func (r *RollupWatcher) scopeContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if r.closed.Load() {
+		return nil, nil, fmt.Errorf("rollup watcher for %v: %w", r.address, ErrClosed)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(r.closeCtx, func() {
+		cancel(ErrClosed)
+	})
+	return ctx, func() {
+		stop()
+		cancel(nil)
 	}, nil
 }
 
-func (r *RollupWatcher) getCallOpts(ctx context.Context) *bind.CallOpts {
+// trackSubscription registers a subscription to be closed along with the watcher.
+func (r *RollupWatcher) trackSubscription(sub event.Subscription) {
+	r.subscriptionsMutex.Lock()
+	defer r.subscriptionsMutex.Unlock()
+	if r.closed.Load() {
+		sub.Unsubscribe()
+		return
+	}
+	r.subscriptions = append(r.subscriptions, sub)
+}
+
+// Close cancels in-flight lookups and closes any subscriptions made through the watcher.
+// It is safe to call multiple times. Watcher methods called after Close return an error wrapping ErrClosed,
+// while lookups that were in flight fail with a cancelled context whose cause is ErrClosed.
+// Calls made directly through the embedded RollupUserLogic binding are unaffected.
+func (r *RollupWatcher) Close() error {
+	if !r.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	r.closeFunc(ErrClosed)
+	r.subscriptionsMutex.Lock()
+	subscriptions := r.subscriptions
+	r.subscriptions = nil
+	r.subscriptionsMutex.Unlock()
+	for _, sub := range subscriptions {
+		sub.Unsubscribe()
+	}
+	return nil
+}
+
+// finalBlock returns the number of the parent chain block with the watcher's finality tag,
+// or nil if lookups aren't limited to final blocks.
+func (r *RollupWatcher) finalBlock(ctx context.Context) (*big.Int, error) {
+	if r.finalityTag == rpc.LatestBlockNumber {
+		return nil, nil
+	}
+	header, err := r.client.HeaderByNumber(ctx, big.NewInt(r.finalityTag.Int64()))
+	if err != nil {
+		return nil, fmt.Errorf("error getting %v parent chain block header: %w", r.finalityTag, err)
+	}
+	return header.Number, nil
+}
+
+// checkNodeFinal returns an error wrapping ErrNodeNotFinal if the watcher has a finality tag
+// and the node was created after the tagged block.
+func (r *RollupWatcher) checkNodeFinal(ctx context.Context, number uint64, createdAtBlock *big.Int) error {
+	finalBlock, err := r.finalBlock(ctx)
+	if err != nil {
+		return err
+	}
+	if finalBlock != nil && createdAtBlock.Cmp(finalBlock) > 0 {
+		return fmt.Errorf("%w: node %v was created in block %v after %v block %v", ErrNodeNotFinal, number, createdAtBlock, r.finalityTag, finalBlock)
+	}
+	return nil
+}
+
+// getCallOpts returns call options bound to ctx, limited by the per-call timeout if one is set.
+// The returned cancel func must be called once the call completes.
+func (r *RollupWatcher) getCallOpts(ctx context.Context) (*bind.CallOpts, context.CancelFunc) {
 	opts := r.baseCallOpts
+	cancel := context.CancelFunc(func() {})
+	if r.callTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.callTimeout)
+	}
 	opts.Context = ctx
-	return &opts
+	return &opts, cancel
 }
 
 const noNodeErr string = "NO_NODE"
 
+// ErrNoNode is returned when the rollup has no node with the requested number.
+var ErrNoNode = errors.New("node does not exist")
+
+// classifyNoNodeError wraps err with ErrNoNode if it looks like the rollup contract rejected a call
+// because the node doesn't exist, so callers can use errors.Is instead of inspecting the message.
+func classifyNoNodeError(err error) error {
+	if err == nil || errors.Is(err, ErrNoNode) || !looksLikeNoNodeError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrNoNode, err)
+}
+
 func looksLikeNoNodeError(err error) bool {
 	if err == nil {
 		return false
@@ -105,50 +495,237 @@ func looksLikeNoNodeError(err error) bool {
 	return bytes.Contains(data, []byte(noNodeErr))
 }
 
+// getNodeCreationBlock returns the parent chain block a node was created in.
+// Creation blocks never change once a node exists, so results are cached until invalidated.
 func (r *RollupWatcher) getNodeCreationBlock(ctx context.Context, nodeNum uint64) (*big.Int, error) {
-	callOpts := r.getCallOpts(ctx)
+	r.cacheMutex.Lock()
+	cached, ok := r.nodeCreationCache.Get(nodeNum)
+	r.cacheMutex.Unlock()
+	if ok {
+		nodeCreationCacheHitCounter.Inc(1)
+		return new(big.Int).Set(cached), nil
+	}
+	nodeCreationCacheMissCounter.Inc(1)
+	createdAtBlock, err := r.lookupNodeCreationBlock(ctx, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	r.cacheMutex.Lock()
+	r.nodeCreationCache.Add(nodeNum, new(big.Int).Set(createdAtBlock))
+	r.cacheMutex.Unlock()
+	return createdAtBlock, nil
+}
+
+// InvalidateNodeCache drops the cached creation block and info of a node, e.g. after a reorg removed it.
+func (r *RollupWatcher) InvalidateNodeCache(nodeNum uint64) {
+	r.cacheMutex.Lock()
+	defer r.cacheMutex.Unlock()
+	r.nodeCreationCache.Remove(nodeNum)
+	r.nodeInfoCache.Remove(nodeNum)
+}
+
+// ResetNodeCache drops all cached node creation blocks, node infos and L1 block numbers, e.g. after a deep reorg.
+func (r *RollupWatcher) ResetNodeCache() {
+	r.cacheMutex.Lock()
+	defer r.cacheMutex.Unlock()
+	r.nodeCreationCache.Clear()
+	r.l1BlockCache.Clear()
+	r.nodeInfoCache.Clear()
+}
+
+// correspondingL1BlockNumber is a cached arbutil.CorrespondingL1BlockNumber.
+// The mapping of a canonical parent chain block never changes, so entries only need dropping after a reorg.
+func (r *RollupWatcher) correspondingL1BlockNumber(ctx context.Context, parentBlockNumber uint64) (uint64, error) {
+	r.cacheMutex.Lock()
+	cached, ok := r.l1BlockCache.Get(parentBlockNumber)
+	r.cacheMutex.Unlock()
+	if ok {
+		l1BlockCacheHitCounter.Inc(1)
+		return cached, nil
+	}
+	l1BlockCacheMissCounter.Inc(1)
+	l1BlockNumber, err := arbutil.CorrespondingL1BlockNumber(ctx, r.client, parentBlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	r.cacheMutex.Lock()
+	r.l1BlockCache.Add(parentBlockNumber, l1BlockNumber)
+	r.cacheMutex.Unlock()
+	return l1BlockNumber, nil
+}
+
+func (r *RollupWatcher) loadL3MethodSupport() {
+	if r.l3MethodSupportStore == nil {
+		return
+	}
+	supported, found, err := r.l3MethodSupportStore.LoadL3MethodSupport()
+	if err != nil {
+		log.Warn("failed to load stored getNodeCreationBlockForLogLookup support, probing it instead", "err", err)
+		return
+	}
+	if !found {
+		return
+	}
+	r.supportedL3Method.Store(supported)
+	r.unSupportedL3Method.Store(!supported)
+	r.l3MethodSupportFromStore.Store(true)
+}
+
+// observeL3MethodSupport records whether getNodeCreationBlockForLogLookup worked, storing the state if it changed.
+func (r *RollupWatcher) observeL3MethodSupport(supported bool) {
+	unchanged := r.supportedL3Method.Load() == supported && r.unSupportedL3Method.Load() == !supported
+	r.supportedL3Method.Store(supported)
+	r.unSupportedL3Method.Store(!supported)
+	r.l3MethodSupportFromStore.Store(false)
+	if r.l3MethodSupportStore == nil || unchanged {
+		return
+	}
+	if err := r.l3MethodSupportStore.StoreL3MethodSupport(supported); err != nil {
+		log.Warn("failed to store getNodeCreationBlockForLogLookup support", "supported", supported, "err", err)
+	}
+}
+
+func (r *RollupWatcher) lookupNodeCreationBlock(ctx context.Context, nodeNum uint64) (*big.Int, error) {
 	if !r.unSupportedL3Method.Load() {
+		callOpts, cancel := r.getCallOpts(ctx)
 		createdAtBlock, err := r.GetNodeCreationBlockForLogLookup(callOpts, nodeNum)
+		cancel()
 		if err == nil {
-			r.supportedL3Method.Store(true)
+			r.observeL3MethodSupport(true)
 			return createdAtBlock, nil
 		}
-		if headerreader.IsExecutionReverted(err) && !looksLikeNoNodeError(err) {
-			if r.supportedL3Method.Load() {
+		err = classifyNoNodeError(err)
+		if headerreader.IsExecutionReverted(err) && !errors.Is(err, ErrNoNode) {
+			if r.supportedL3Method.Load() && !r.l3MethodSupportFromStore.Load() {
 				return nil, fmt.Errorf("getNodeCreationBlockForLogLookup failed despite previously succeeding: %w", err)
 			}
 			log.Info("getNodeCreationBlockForLogLookup does not seem to exist, falling back on node CreatedAtBlock field", "err", err)
-			r.unSupportedL3Method.Store(true)
+			r.observeL3MethodSupport(false)
 		} else {
 			return nil, err
 		}
 	}
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
 	node, err := r.GetNode(callOpts, nodeNum)
 	if err != nil {
-		return nil, err
+		return nil, classifyNoNodeError(err)
 	}
 	createdAtBlock := new(big.Int).SetUint64(node.CreatedAtBlock)
 	return createdAtBlock, nil
 }
 
 func (r *RollupWatcher) Initialize(ctx context.Context) error {
-	var err error
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if r.initializeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, r.initializeTimeout, errInitializeTimeout)
+		defer cancel()
+	}
 	r.fromBlock, err = r.getNodeCreationBlock(ctx, 0)
+	if err != nil && errors.Is(context.Cause(ctx), errInitializeTimeout) {
+		return fmt.Errorf("rollup watcher for %v did not initialize within %v, the parent chain endpoint may be slow or unresponsive: %w", r.address, r.initializeTimeout, err)
+	}
 	return err
 }
 
+// HealthCheck makes sure the rollup contract is deployed and answers a cheap call,
+// so misconfiguration shows up at startup rather than partway through a scan.
+func (r *RollupWatcher) HealthCheck(ctx context.Context) error {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	code, err := r.client.CodeAt(ctx, r.address, nil)
+	if err != nil {
+		return fmt.Errorf("error getting code of rollup contract %v: %w", r.address, err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("no contract code at rollup address %v", r.address)
+	}
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
+	if _, err := r.LatestConfirmed(callOpts); err != nil {
+		return fmt.Errorf("rollup contract %v failed to return latest confirmed node: %w", r.address, err)
+	}
+	return nil
+}
+
+// FromBlock returns the parent chain block the rollup was created in, as found by Initialize.
+func (r *RollupWatcher) FromBlock() (*big.Int, error) {
+	if r.fromBlock == nil {
+		return nil, errors.New("rollup watcher not initialized")
+	}
+	return new(big.Int).Set(r.fromBlock), nil
+}
+
 func (r *RollupWatcher) Client() RollupWatcherL1Interface {
 	return r.client
 }
 
+// RollupABI returns the parsed rollup ABI the watcher finds events with, for callers building their own
+// log queries against Client, e.g. with RollupABI().Events[name].ID as a topic, or parsing other events.
+// It's shared by every watcher and must be treated as read-only.
+func (r *RollupWatcher) RollupABI() *abi.ABI {
+	return rollupABI
+}
+
+// GetNodeInfo returns the on-chain state of a node, or an error wrapping ErrNoNode if it doesn't exist.
+func (r *RollupWatcher) GetNodeInfo(ctx context.Context, nodeNum uint64) (*NodeState, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
+	node, err := r.RollupUserLogic.GetNode(callOpts, nodeNum)
+	if err != nil {
+		return nil, fmt.Errorf("error getting node %v: %w", nodeNum, classifyNoNodeError(err))
+	}
+	// nodes that were never created are returned zeroed rather than reverting
+	if node.NodeHash == (common.Hash{}) {
+		return nil, fmt.Errorf("node %v: %w", nodeNum, ErrNoNode)
+	}
+	return &NodeState{
+		StateHash:                   node.StateHash,
+		ChallengeHash:               node.ChallengeHash,
+		ConfirmData:                 node.ConfirmData,
+		PrevNum:                     node.PrevNum,
+		DeadlineBlock:               node.DeadlineBlock,
+		NoChildConfirmedBeforeBlock: node.NoChildConfirmedBeforeBlock,
+		StakerCount:                 node.StakerCount,
+		ChildStakerCount:            node.ChildStakerCount,
+		FirstChildBlock:             node.FirstChildBlock,
+		LatestChildNumber:           node.LatestChildNumber,
+		CreatedAtBlock:              node.CreatedAtBlock,
+		NodeHash:                    node.NodeHash,
+	}, nil
+}
+
+// LookupCreation returns the rollup's RollupInitialized event. It never changes,
+// so it's only fetched once and later calls return a copy of the first result.
 func (r *RollupWatcher) LookupCreation(ctx context.Context) (*rollup_legacy_gen.RollupUserLogicRollupInitialized, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if cached := r.creation.Load(); cached != nil {
+		return copyRollupInitialized(cached), nil
+	}
 	var query = ethereum.FilterQuery{
 		FromBlock: r.fromBlock,
 		ToBlock:   r.fromBlock,
 		Addresses: []common.Address{r.address},
 		Topics:    [][]common.Hash{{rollupInitializedID}},
 	}
-	logs, err := r.client.FilterLogs(ctx, query)
+	logs, err := r.filterLogs(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -159,14 +736,46 @@ func (r *RollupWatcher) LookupCreation(ctx context.Context) (*rollup_legacy_gen.
 		return nil, errors.New("rollup created multiple times")
 	}
 	ev, err := r.ParseRollupInitialized(logs[0])
-	return ev, err
+	if err != nil {
+		return nil, err
+	}
+	r.creation.Store(copyRollupInitialized(ev))
+	return ev, nil
+}
+
+func copyRollupInitialized(ev *rollup_legacy_gen.RollupUserLogicRollupInitialized) *rollup_legacy_gen.RollupUserLogicRollupInitialized {
+	evCopy := *ev
+	if ev.ChainId != nil {
+		evCopy.ChainId = new(big.Int).Set(ev.ChainId)
+	}
+	return &evCopy
 }
 
 func (r *RollupWatcher) LookupNode(ctx context.Context, number uint64) (*NodeInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if info, ok := r.cachedNodeInfo(number); ok {
+		return info, nil
+	}
+	info, err := r.lookupNode(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	r.cacheNodeInfo(ctx, info)
+	return info, nil
+}
+
+func (r *RollupWatcher) lookupNode(ctx context.Context, number uint64) (*NodeInfo, error) {
 	createdAtBlock, err := r.getNodeCreationBlock(ctx, number)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.checkNodeFinal(ctx, number, createdAtBlock); err != nil {
+		return nil, err
+	}
 	var numberAsHash common.Hash
 	binary.BigEndian.PutUint64(numberAsHash[(32-8):], number)
 	var query = ethereum.FilterQuery{
@@ -175,12 +784,34 @@ func (r *RollupWatcher) LookupNode(ctx context.Context, number uint64) (*NodeInf
 		Addresses: []common.Address{r.address},
 		Topics:    [][]common.Hash{{nodeCreatedID}, {numberAsHash}},
 	}
-	logs, err := r.client.FilterLogs(ctx, query)
+	logs, err := r.filterLogs(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	if len(logs) == 0 && r.reprobeStoredL3MethodSupport(number) {
+		return r.lookupNode(ctx, number)
+	}
+	return r.nodeInfoFromCreationLogs(ctx, number, logs)
+}
+
+// reprobeStoredL3MethodSupport is called when a node's creation log wasn't found at its creation block.
+// A stored unsupported L3 method state may be stale, in which case the fallback creation block is wrong,
+// so it's dropped for the L3 method to be probed again. It returns whether the lookup should be retried.
+func (r *RollupWatcher) reprobeStoredL3MethodSupport(number uint64) bool {
+	if !r.unSupportedL3Method.Load() || !r.l3MethodSupportFromStore.CompareAndSwap(true, false) {
+		return false
+	}
+	log.Info("node not found at creation block using stored getNodeCreationBlockForLogLookup support, probing it again", "node", number)
+	r.unSupportedL3Method.Store(false)
+	r.InvalidateNodeCache(number)
+	return true
+}
+
+// nodeInfoFromCreationLogs builds the NodeInfo for a node from the NodeCreated logs found for it at its creation block,
+// checking there's exactly one and that its block is still canonical.
+func (r *RollupWatcher) nodeInfoFromCreationLogs(ctx context.Context, number uint64, logs []types.Log) (*NodeInfo, error) {
 	if len(logs) == 0 {
-		return nil, fmt.Errorf("couldn't find requested node %v", number)
+		return nil, fmt.Errorf("couldn't find requested node %v: %w", number, ErrNoNode)
 	}
 	if len(logs) > 1 {
 		return nil, fmt.Errorf("found multiple instances of requested node %v", number)
@@ -190,10 +821,16 @@ func (r *RollupWatcher) LookupNode(ctx context.Context, number uint64) (*NodeInf
 	if err != nil {
 		return nil, err
 	}
-	l1BlockProposed, err := arbutil.CorrespondingL1BlockNumber(ctx, r.client, ethLog.BlockNumber)
+	header, err := r.client.HeaderByNumber(ctx, new(big.Int).SetUint64(ethLog.BlockNumber))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error getting parent chain block %d header: %w", ethLog.BlockNumber, err)
+	}
+	// the parent chain may have reorged between looking up the creation block and fetching the log
+	if header.Hash() != ethLog.BlockHash {
+		r.InvalidateOnReorg(ethLog.BlockNumber)
+		return nil, fmt.Errorf("%w: node %v log in block %v has hash %v but canonical block hash is %v", ErrReorgDetected, number, ethLog.BlockNumber, ethLog.BlockHash, header.Hash())
 	}
+	l1BlockProposed := arbutil.ParentHeaderToL1BlockNumber(header)
 	return &NodeInfo{
 		NodeNum:                  parsedLog.NodeNum,
 		L1BlockProposed:          l1BlockProposed,
@@ -206,16 +843,89 @@ func (r *RollupWatcher) LookupNode(ctx context.Context, number uint64) (*NodeInf
 	}, nil
 }
 
+// LookupNodes looks up several nodes concurrently, returning them in the same order as nodeNums.
+// The first failure cancels the remaining lookups and is returned.
+func (r *RollupWatcher) LookupNodes(ctx context.Context, nodeNums []uint64) ([]*NodeInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	infos := make([]*NodeInfo, len(nodeNums))
+	g, gctx := errgroup.WithContext(ctx)
+	if r.nodeLookupConcurrency > 0 {
+		g.SetLimit(r.nodeLookupConcurrency)
+	}
+	for i, nodeNum := range nodeNums {
+		g.Go(func() error {
+			info, err := r.LookupNode(gctx, nodeNum)
+			if err != nil {
+				return err
+			}
+			infos[i] = info
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// LookupNodeChildren returns the children of a node in creation order.
+// A logQueryRangeSize of 0 uses the watcher's default log query range size.
 func (r *RollupWatcher) LookupNodeChildren(ctx context.Context, nodeNum uint64, logQueryRangeSize uint64, nodeHash common.Hash) ([]*NodeInfo, error) {
-	node, err := r.RollupUserLogic.GetNode(r.getCallOpts(ctx), nodeNum)
+	ctx, release, err := r.scopeContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if node.LatestChildNumber == 0 {
+	defer release()
+	query, fromBlock, toBlock, err := r.childrenQuery(ctx, nodeNum, nodeHash)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
 		return nil, nil
 	}
+	if logQueryRangeSize == 0 {
+		logQueryRangeSize = r.defaultLogQueryRangeSize
+	}
+	logs, err := r.filterLogsInRange(ctx, *query, fromBlock, toBlock, logQueryRangeSize, r.maxChildren, r.childrenProgressFunc)
+	var limitErr *logLimitError
+	if errors.As(err, &limitErr) {
+		return nil, &TooManyChildrenError{NodeNum: nodeNum, Count: limitErr.count, Limit: limitErr.limit}
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*NodeInfo, 0, len(logs))
+	lastHash := nodeHash
+	for i, ethLog := range logs {
+		info, err := r.childNodeInfo(ctx, ethLog, lastHash, i > 0)
+		if err != nil {
+			return nil, err
+		}
+		lastHash = info.NodeHash
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// childrenQuery checks nodeNum still has hash nodeHash and returns the query matching its children's NodeCreated logs,
+// along with the parent chain block range they were created in, clamped to the final block if the watcher has a
+// finality tag. The query is nil if the node has no (final) children.
+func (r *RollupWatcher) childrenQuery(ctx context.Context, nodeNum uint64, nodeHash common.Hash) (*ethereum.FilterQuery, *big.Int, *big.Int, error) {
+	callOpts, cancel := r.getCallOpts(ctx)
+	node, err := r.RollupUserLogic.GetNode(callOpts, nodeNum)
+	cancel()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if node.LatestChildNumber == 0 {
+		return nil, nil, nil, nil
+	}
 	if node.NodeHash != nodeHash {
-		return nil, fmt.Errorf("got unexpected node hash %v looking for node number %v with expected hash %v (reorg?)", node.NodeHash, nodeNum, nodeHash)
+		return nil, nil, nil, fmt.Errorf("got unexpected node hash %v looking for node number %v with expected hash %v (reorg?)", node.NodeHash, nodeNum, nodeHash)
 	}
 	var query = ethereum.FilterQuery{
 		Addresses: []common.Address{r.address},
@@ -223,63 +933,361 @@ func (r *RollupWatcher) LookupNodeChildren(ctx context.Context, nodeNum uint64,
 	}
 	fromBlock, err := r.getNodeCreationBlock(ctx, nodeNum)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	toBlock, err := r.getNodeCreationBlock(ctx, node.LatestChildNumber)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	finalBlock, err := r.finalBlock(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if finalBlock != nil && toBlock.Cmp(finalBlock) > 0 {
+		if fromBlock.Cmp(finalBlock) > 0 {
+			return nil, nil, nil, nil
+		}
+		toBlock = finalBlock
+	}
+	return &query, fromBlock, toBlock, nil
+}
+
+// childNodeInfo builds the NodeInfo for a child's NodeCreated log, computing its hash from the hash of the node
+// before it: the parent for the first child, or the previous sibling otherwise.
+func (r *RollupWatcher) childNodeInfo(ctx context.Context, ethLog types.Log, prevHash common.Hash, prevIsSibling bool) (*NodeInfo, error) {
+	parsedLog, err := r.ParseNodeCreated(ethLog)
 	if err != nil {
 		return nil, err
 	}
-	var logs []types.Log
-	// break down the query to avoid eth_getLogs query limit
-	for toBlock.Cmp(fromBlock) > 0 {
-		query.FromBlock = fromBlock
-		if logQueryRangeSize == 0 {
-			query.ToBlock = toBlock
-		} else {
-			query.ToBlock = new(big.Int).Add(fromBlock, new(big.Int).SetUint64(logQueryRangeSize))
+	nodeHash := r.childNodeHash(prevHash, prevIsSibling, parsedLog.ExecutionHash, parsedLog.AfterInboxBatchAcc, parsedLog.WasmModuleRoot)
+	l1BlockProposed, err := r.correspondingL1BlockNumber(ctx, ethLog.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeInfo{
+		NodeNum:                  parsedLog.NodeNum,
+		L1BlockProposed:          l1BlockProposed,
+		ParentChainBlockProposed: ethLog.BlockNumber,
+		Assertion:                NewAssertionFromLegacySolidity(parsedLog.Assertion),
+		InboxMaxCount:            parsedLog.InboxMaxCount,
+		AfterInboxBatchAcc:       parsedLog.AfterInboxBatchAcc,
+		NodeHash:                 nodeHash,
+		WasmModuleRoot:           parsedLog.WasmModuleRoot,
+	}, nil
+}
+
+// childNodeHash computes a child's node hash from the hash of the node before it, which is its previous sibling
+// if prevIsSibling, or its parent otherwise, and the execution hash, inbox accumulator and wasm module root it was
+// created with.
+func (r *RollupWatcher) childNodeHash(prevHash common.Hash, prevIsSibling bool, executionHash common.Hash, afterInboxBatchAcc common.Hash, wasmModuleRoot common.Hash) common.Hash {
+	hash := r.nodeHashFunc
+	if hash == nil {
+		hash = crypto.Keccak256Hash
+	}
+	lastHashIsSibling := [1]byte{0}
+	if prevIsSibling {
+		lastHashIsSibling[0] = 1
+	}
+	return hash(lastHashIsSibling[:], prevHash[:], executionHash[:], afterInboxBatchAcc[:], wasmModuleRoot[:])
+}
+
+// nodeInfoFromLog builds the NodeInfo for a NodeCreated event, using the node hash the event reports.
+func (r *RollupWatcher) nodeInfoFromLog(ctx context.Context, ethLog types.Log, parsedLog *rollup_legacy_gen.RollupUserLogicNodeCreated) (*NodeInfo, error) {
+	l1BlockProposed, err := r.correspondingL1BlockNumber(ctx, ethLog.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeInfo{
+		NodeNum:                  parsedLog.NodeNum,
+		L1BlockProposed:          l1BlockProposed,
+		ParentChainBlockProposed: ethLog.BlockNumber,
+		Assertion:                NewAssertionFromLegacySolidity(parsedLog.Assertion),
+		InboxMaxCount:            parsedLog.InboxMaxCount,
+		AfterInboxBatchAcc:       parsedLog.AfterInboxBatchAcc,
+		NodeHash:                 parsedLog.NodeHash,
+		WasmModuleRoot:           parsedLog.WasmModuleRoot,
+	}, nil
+}
+
+// NodeRangeOption narrows down the nodes LookupNodesInRange and WalkNodes return.
+type NodeRangeOption func(*nodeRangeConfig)
+
+type nodeRangeConfig struct {
+	wasmModuleRoot *common.Hash
+}
+
+// MatchingWasmModuleRoot only keeps nodes asserted with the given WasmModuleRoot.
+func MatchingWasmModuleRoot(root common.Hash) NodeRangeOption {
+	return func(c *nodeRangeConfig) {
+		c.wasmModuleRoot = &root
+	}
+}
+
+func newNodeRangeConfig(opts []NodeRangeOption) *nodeRangeConfig {
+	config := &nodeRangeConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// matches is checked right after parsing a node's log, so that skipped nodes cost no further RPCs.
+func (c *nodeRangeConfig) matches(parsedLog *rollup_legacy_gen.RollupUserLogicNodeCreated) bool {
+	return c.wasmModuleRoot == nil || parsedLog.WasmModuleRoot == *c.wasmModuleRoot
+}
+
+// LookupNodesInRange returns the nodes numbered fromNode through toNode (inclusive) in ascending order,
+// leaving out those that don't match opts.
+// It scans the NodeCreated logs between the two nodes' creation blocks using the default log query range size,
+// and fails with ErrNoNode if any node in the range is missing.
+func (r *RollupWatcher) LookupNodesInRange(ctx context.Context, fromNode uint64, toNode uint64, opts ...NodeRangeOption) ([]*NodeInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if fromNode > toNode {
+		return nil, fmt.Errorf("invalid node range %v to %v", fromNode, toNode)
+	}
+	fromBlock, err := r.getNodeCreationBlock(ctx, fromNode)
+	if err != nil {
+		return nil, err
+	}
+	toBlock, err := r.getNodeCreationBlock(ctx, toNode)
+	if err != nil {
+		return nil, err
+	}
+	var query = ethereum.FilterQuery{
+		Addresses: []common.Address{r.address},
+		Topics:    [][]common.Hash{{nodeCreatedID}},
+	}
+	logs, err := r.filterLogsInRange(ctx, query, fromBlock, toBlock, r.defaultLogQueryRangeSize, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	config := newNodeRangeConfig(opts)
+	// nodes holds nil for nodes that were found but don't match, so missing nodes are still detected
+	nodes := make(map[uint64]*NodeInfo)
+	for _, ethLog := range logs {
+		parsedLog, err := r.ParseNodeCreated(ethLog)
+		if err != nil {
+			return nil, err
+		}
+		if parsedLog.NodeNum < fromNode || parsedLog.NodeNum > toNode {
+			continue
 		}
-		if query.ToBlock.Cmp(toBlock) > 0 {
-			query.ToBlock = toBlock
+		if _, ok := nodes[parsedLog.NodeNum]; ok {
+			continue
 		}
-		segment, err := r.client.FilterLogs(ctx, query)
+		if !config.matches(parsedLog) {
+			nodes[parsedLog.NodeNum] = nil
+			continue
+		}
+		nodes[parsedLog.NodeNum], err = r.nodeInfoFromLog(ctx, ethLog, parsedLog)
 		if err != nil {
 			return nil, err
 		}
-		logs = append(logs, segment...)
-		fromBlock = new(big.Int).Add(query.ToBlock, big.NewInt(1))
 	}
-	infos := make([]*NodeInfo, 0, len(logs))
-	lastHash := nodeHash
-	for i, ethLog := range logs {
-		parsedLog, err := r.ParseNodeCreated(ethLog)
+	infos := make([]*NodeInfo, 0, len(nodes))
+	for i := uint64(0); i <= toNode-fromNode; i++ {
+		info, ok := nodes[fromNode+i]
+		if !ok {
+			return nil, fmt.Errorf("node %v missing from logs between blocks %v and %v: %w", fromNode+i, fromBlock, toBlock, ErrNoNode)
+		}
+		if info != nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// WalkNodes calls fn with each node numbered fromNode through toNode (inclusive) that matches opts, in ascending order,
+// as soon as the log segment containing it has been fetched, without holding the whole range in memory.
+// Walking stops at the first error returned by fn, which is passed back to the caller.
+func (r *RollupWatcher) WalkNodes(ctx context.Context, fromNode uint64, toNode uint64, fn func(*NodeInfo) error, opts ...NodeRangeOption) error {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if fromNode > toNode {
+		return fmt.Errorf("invalid node range %v to %v", fromNode, toNode)
+	}
+	fromBlock, err := r.getNodeCreationBlock(ctx, fromNode)
+	if err != nil {
+		return err
+	}
+	toBlock, err := r.getNodeCreationBlock(ctx, toNode)
+	if err != nil {
+		return err
+	}
+	var query = ethereum.FilterQuery{
+		Addresses: []common.Address{r.address},
+		Topics:    [][]common.Hash{{nodeCreatedID}},
+	}
+	config := newNodeRangeConfig(opts)
+	var maxRangeSize atomic.Uint64
+	nextNode := fromNode
+	for _, segment := range splitBlockRange(fromBlock, toBlock, r.defaultLogQueryRangeSize) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		logs, err := r.filterLogsAdaptive(ctx, query, segment.from, segment.to, &maxRangeSize)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		for _, ethLog := range logs {
+			parsedLog, err := r.ParseNodeCreated(ethLog)
+			if err != nil {
+				return err
+			}
+			// nodes are created in order, so anything below nextNode was already visited or is out of range
+			if parsedLog.NodeNum < nextNode || parsedLog.NodeNum > toNode {
+				continue
+			}
+			if !config.matches(parsedLog) {
+				nextNode = parsedLog.NodeNum + 1
+				continue
+			}
+			info, err := r.nodeInfoFromLog(ctx, ethLog, parsedLog)
+			if err != nil {
+				return err
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+			nextNode = parsedLog.NodeNum + 1
 		}
-		lastHashIsSibling := [1]byte{0}
-		if i > 0 {
-			lastHashIsSibling[0] = 1
+	}
+	return nil
+}
+
+// WasmRootChange records a node whose WasmModuleRoot differs from that of the node numbered before it.
+type WasmRootChange struct {
+	NodeNum  uint64
+	PrevRoot common.Hash
+	NewRoot  common.Hash
+}
+
+// WasmModuleRootChanges returns the nodes numbered fromNode through toNode (inclusive) whose WasmModuleRoot
+// differs from the previous node's, in ascending order. The node before fromNode is scanned too, so a change
+// at fromNode itself is reported; the genesis node has no previous node and is never reported.
+func (r *RollupWatcher) WasmModuleRootChanges(ctx context.Context, fromNode uint64, toNode uint64) ([]WasmRootChange, error) {
+	if fromNode > toNode {
+		return nil, fmt.Errorf("invalid node range %v to %v", fromNode, toNode)
+	}
+	scanFrom := fromNode
+	if scanFrom > 0 {
+		scanFrom--
+	}
+	var changes []WasmRootChange
+	var prev *NodeInfo
+	err := r.WalkNodes(ctx, scanFrom, toNode, func(info *NodeInfo) error {
+		if prev != nil && prev.WasmModuleRoot != info.WasmModuleRoot {
+			changes = append(changes, WasmRootChange{
+				NodeNum:  info.NodeNum,
+				PrevRoot: prev.WasmModuleRoot,
+				NewRoot:  info.WasmModuleRoot,
+			})
 		}
-		lastHash = crypto.Keccak256Hash(lastHashIsSibling[:], lastHash[:], parsedLog.ExecutionHash[:], parsedLog.AfterInboxBatchAcc[:], parsedLog.WasmModuleRoot[:])
-		l1BlockProposed, err := arbutil.CorrespondingL1BlockNumber(ctx, r.client, ethLog.BlockNumber)
+		prev = info
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// TooManyChildrenError reports a node with more children than LookupNodeChildren was allowed to collect.
+// Count is how many children had been seen when the scan stopped.
+type TooManyChildrenError struct {
+	NodeNum uint64
+	Count   uint64
+	Limit   uint64
+}
+
+func (e *TooManyChildrenError) Error() string {
+	return fmt.Sprintf("%v: node %v has at least %v children, more than the limit of %v", ErrTooManyChildren, e.NodeNum, e.Count, e.Limit)
+}
+
+func (e *TooManyChildrenError) Is(target error) bool {
+	return target == ErrTooManyChildren
+}
+
+// NodeHashMismatchError reports a child node whose on-chain hash differs from the one recomputed from its logs.
+type NodeHashMismatchError struct {
+	NodeNum  uint64
+	Expected common.Hash
+	Computed common.Hash
+}
+
+func (e *NodeHashMismatchError) Error() string {
+	return fmt.Sprintf("node %v has on-chain hash %v but its logs hash to %v", e.NodeNum, e.Expected, e.Computed)
+}
+
+// VerifyNodeChain recomputes the hashes of a node's children from their NodeCreated logs and checks each against
+// the hash stored on-chain, returning a *NodeHashMismatchError for the first child that differs.
+func (r *RollupWatcher) VerifyNodeChain(ctx context.Context, nodeNum uint64, nodeHash common.Hash) error {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	children, err := r.LookupNodeChildren(ctx, nodeNum, 0, nodeHash)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		callOpts, cancel := r.getCallOpts(ctx)
+		node, err := r.GetNode(callOpts, child.NodeNum)
+		cancel()
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("error getting node %v: %w", child.NodeNum, classifyNoNodeError(err))
+		}
+		if node.NodeHash != child.NodeHash {
+			return &NodeHashMismatchError{
+				NodeNum:  child.NodeNum,
+				Expected: node.NodeHash,
+				Computed: child.NodeHash,
+			}
 		}
-		infos = append(infos, &NodeInfo{
-			NodeNum:                  parsedLog.NodeNum,
-			L1BlockProposed:          l1BlockProposed,
-			ParentChainBlockProposed: ethLog.BlockNumber,
-			Assertion:                NewAssertionFromLegacySolidity(parsedLog.Assertion),
-			InboxMaxCount:            parsedLog.InboxMaxCount,
-			AfterInboxBatchAcc:       parsedLog.AfterInboxBatchAcc,
-			NodeHash:                 lastHash,
-			WasmModuleRoot:           parsedLog.WasmModuleRoot,
-		})
 	}
-	return infos, nil
+	return nil
+}
+
+// LatestCreatedNode returns the number of the most recently created node.
+// A rollup that only has its genesis node returns 0.
+func (r *RollupWatcher) LatestCreatedNode(ctx context.Context) (uint64, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
+	return r.LatestNodeCreated(callOpts)
+}
+
+// NodeCount returns how many nodes the rollup has created, including the genesis node.
+// Nodes are numbered consecutively from 0, so this bounds scans over all nodes.
+func (r *RollupWatcher) NodeCount(ctx context.Context) (uint64, error) {
+	latest, err := r.LatestCreatedNode(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return latest + 1, nil
 }
 
 func (r *RollupWatcher) LatestConfirmedCreationBlock(ctx context.Context) (uint64, error) {
-	latestConfirmed, err := r.LatestConfirmed(r.getCallOpts(ctx))
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	latestConfirmed, err := r.LatestConfirmed(callOpts)
+	cancel()
 	if err != nil {
 		return 0, err
 	}
@@ -294,6 +1302,11 @@ func (r *RollupWatcher) LatestConfirmedCreationBlock(ctx context.Context) (uint6
 }
 
 func (r *RollupWatcher) LookupChallengedNode(ctx context.Context, address common.Address) (uint64, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
 	// TODO: This function is currently unused
 
 	// Assuming this function is only used to find information about an active challenge, it
@@ -307,13 +1320,15 @@ func (r *RollupWatcher) LookupChallengedNode(ctx context.Context, address common
 	addressQuery := common.Hash{}
 	copy(addressQuery[12:], address.Bytes())
 
+	head, err := r.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error getting parent chain head: %w", err)
+	}
 	query := ethereum.FilterQuery{
-		FromBlock: new(big.Int).SetUint64(latestConfirmedCreated),
-		ToBlock:   nil,
 		Addresses: []common.Address{r.address},
 		Topics:    [][]common.Hash{{challengeCreatedID}, {addressQuery}},
 	}
-	logs, err := r.client.FilterLogs(ctx, query)
+	logs, err := r.filterLogsInRange(ctx, query, new(big.Int).SetUint64(latestConfirmedCreated), head.Number, r.defaultLogQueryRangeSize, 0, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -335,7 +1350,14 @@ func (r *RollupWatcher) LookupChallengedNode(ctx context.Context, address common
 }
 
 func (r *RollupWatcher) StakerInfo(ctx context.Context, staker common.Address) (*StakerInfo, error) {
-	info, err := r.StakerMap(r.getCallOpts(ctx), staker)
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
+	info, err := r.StakerMap(callOpts, staker)
 	if err != nil {
 		return nil, err
 	}
@@ -353,3 +1375,109 @@ func (r *RollupWatcher) StakerInfo(ctx context.Context, staker common.Address) (
 	}
 	return stakerInfo, nil
 }
+
+// WithdrawableFundsFor returns how much the given address can withdraw from the rollup, such as a stake that was
+// returned. Addresses without any withdrawable funds get zero.
+func (r *RollupWatcher) WithdrawableFundsFor(ctx context.Context, owner common.Address) (*big.Int, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
+	funds, err := r.RollupUserLogic.WithdrawableFunds(callOpts, owner)
+	if err != nil {
+		return nil, fmt.Errorf("error getting withdrawable funds of %v: %w", owner, err)
+	}
+	if funds == nil {
+		return new(big.Int), nil
+	}
+	return funds, nil
+}
+
+// StakerInfos looks up several stakers concurrently. Like StakerInfo, addresses that aren't staked map to nil.
+func (r *RollupWatcher) StakerInfos(ctx context.Context, stakers []common.Address) (map[common.Address]*StakerInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	infos := make(map[common.Address]*StakerInfo, len(stakers))
+	var infosMutex sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	if r.stakerLookupConcurrency > 0 {
+		g.SetLimit(r.stakerLookupConcurrency)
+	}
+	for _, staker := range stakers {
+		g.Go(func() error {
+			info, err := r.StakerInfo(gctx, staker)
+			if err != nil {
+				return fmt.Errorf("error getting staker %v info: %w", staker, err)
+			}
+			infosMutex.Lock()
+			infos[staker] = info
+			infosMutex.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// IterateStakers calls fn for every staker on the rollup in index order, fetching stakers in batches of the
+// watcher's staker lookup concurrency. Iteration stops at the first error returned by fn, which is passed back.
+// Stakers that join or leave while iterating may be missed or visited out of order, as the rollup reorders
+// its staker list when a staker is removed.
+func (r *RollupWatcher) IterateStakers(ctx context.Context, fn func(common.Address, *StakerInfo) error) error {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	count, err := r.StakerCount(callOpts)
+	cancel()
+	if err != nil {
+		return err
+	}
+	batchSize := count
+	if r.stakerLookupConcurrency > 0 {
+		// #nosec G115
+		batchSize = uint64(r.stakerLookupConcurrency)
+	}
+	for start := uint64(0); start < count; start += batchSize {
+		end := min(start+batchSize, count)
+		addresses := make([]common.Address, end-start)
+		g, gctx := errgroup.WithContext(ctx)
+		for i := range addresses {
+			// #nosec G115
+			index := start + uint64(i)
+			g.Go(func() error {
+				callOpts, cancel := r.getCallOpts(gctx)
+				defer cancel()
+				address, err := r.GetStakerAddress(callOpts, index)
+				if err != nil {
+					return fmt.Errorf("error getting staker %v address: %w", index, err)
+				}
+				addresses[i] = address
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+		infos, err := r.StakerInfos(ctx, addresses)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			if err := fn(address, infos[address]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
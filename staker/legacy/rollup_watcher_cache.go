@@ -0,0 +1,101 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+type nodeInfoCacheEntry struct {
+	info *NodeInfo
+	// expiresAt is zero for resolved nodes, which never change
+	expiresAt time.Time
+}
+
+// cachedNodeInfo returns a copy of the cached info of a node, unless it's missing or expired.
+func (r *RollupWatcher) cachedNodeInfo(nodeNum uint64) (*NodeInfo, bool) {
+	if r.nodeInfoCacheSize <= 0 {
+		return nil, false
+	}
+	r.cacheMutex.Lock()
+	entry, ok := r.nodeInfoCache.Get(nodeNum)
+	if ok && !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		r.nodeInfoCache.Remove(nodeNum)
+		ok = false
+	}
+	r.cacheMutex.Unlock()
+	if !ok {
+		nodeInfoCacheMissCounter.Inc(1)
+		return nil, false
+	}
+	nodeInfoCacheHitCounter.Inc(1)
+	infoCopy := *entry.info
+	return &infoCopy, true
+}
+
+// cacheNodeInfo caches a copy of info, permanently if the node is at or before the latest confirmed node,
+// or for the node info TTL otherwise.
+func (r *RollupWatcher) cacheNodeInfo(ctx context.Context, info *NodeInfo) {
+	if r.nodeInfoCacheSize <= 0 {
+		return
+	}
+	var expiresAt time.Time
+	if info.NodeNum > r.latestConfirmedSeen.Load() {
+		callOpts, cancel := r.getCallOpts(ctx)
+		latestConfirmed, err := r.LatestConfirmed(callOpts)
+		cancel()
+		if err != nil {
+			log.Debug("failed to get latest confirmed node to cache node info, not caching it", "node", info.NodeNum, "err", err)
+			return
+		}
+		r.observeLatestConfirmed(latestConfirmed)
+		if info.NodeNum > latestConfirmed {
+			expiresAt = time.Now().Add(r.nodeInfoCacheTTL)
+		}
+	}
+	infoCopy := *info
+	r.cacheMutex.Lock()
+	defer r.cacheMutex.Unlock()
+	r.nodeInfoCache.Add(info.NodeNum, nodeInfoCacheEntry{info: &infoCopy, expiresAt: expiresAt})
+}
+
+// observeLatestConfirmed raises latestConfirmedSeen to latestConfirmed; the latest confirmed node never decreases.
+func (r *RollupWatcher) observeLatestConfirmed(latestConfirmed uint64) {
+	for {
+		current := r.latestConfirmedSeen.Load()
+		if current >= latestConfirmed || r.latestConfirmedSeen.CompareAndSwap(current, latestConfirmed) {
+			return
+		}
+	}
+}
+
+// InvalidateOnReorg evicts every cached entry backed by a parent chain block at or after fromBlock:
+// node creation blocks, node infos, L1 block numbers and the rollup creation event.
+// The watcher calls it itself when it detects a reorg, and callers running their own reorg detection
+// should call it with the first reorged block.
+func (r *RollupWatcher) InvalidateOnReorg(fromBlock uint64) {
+	if creation := r.creation.Load(); creation != nil && creation.Raw.BlockNumber >= fromBlock {
+		r.creation.CompareAndSwap(creation, nil)
+	}
+	r.cacheMutex.Lock()
+	defer r.cacheMutex.Unlock()
+	for _, nodeNum := range r.nodeCreationCache.Keys() {
+		if createdAtBlock, ok := r.nodeCreationCache.Peek(nodeNum); ok && createdAtBlock.Uint64() >= fromBlock {
+			r.nodeCreationCache.Remove(nodeNum)
+		}
+	}
+	for _, nodeNum := range r.nodeInfoCache.Keys() {
+		if entry, ok := r.nodeInfoCache.Peek(nodeNum); ok && entry.info.ParentChainBlockProposed >= fromBlock {
+			r.nodeInfoCache.Remove(nodeNum)
+		}
+	}
+	for _, parentBlock := range r.l1BlockCache.Keys() {
+		if parentBlock >= fromBlock {
+			r.l1BlockCache.Remove(parentBlock)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/solgen/go/challenge_legacy_gen"
+	"github.com/offchainlabs/nitro/solgen/go/rollup_legacy_gen"
+)
+
+// The challenge manager clears a challenge once it's resolved, leaving its mode as none.
+const challengeModeNone = 0
+
+// ChallengeInfo describes a challenge of a node between its asserter and a challenger.
+type ChallengeInfo struct {
+	Index          uint64
+	Asserter       common.Address
+	Challenger     common.Address
+	ChallengedNode uint64
+	// Opponent and State are only filled in by StakerChallenge.
+	// Opponent is the other party of the challenge from the looked up staker's point of view.
+	Opponent common.Address
+	State    *ChallengeState
+}
+
+// ChallengeState is a challenge's progress as held by the challenge manager.
+type ChallengeState struct {
+	// Mode is 1 while bisecting blocks and 2 once the challenge is over a single block's execution.
+	Mode uint8
+	// CurrentResponder is the party whose turn it is, with CurrentTimeLeft seconds left on their clock.
+	CurrentResponder   common.Address
+	CurrentTimeLeft    *big.Int
+	NextResponder      common.Address
+	NextTimeLeft       *big.Int
+	LastMoveTimestamp  *big.Int
+	WasmModuleRoot     common.Hash
+	ChallengeStateHash common.Hash
+	MaxInboxMessages   uint64
+}
+
+// challengeManager returns a binding to the rollup's challenge manager, which is looked up once.
+func (r *RollupWatcher) challengeManager(ctx context.Context) (*challenge_legacy_gen.ChallengeManager, error) {
+	if con := r.challengeManagerCon.Load(); con != nil {
+		return con, nil
+	}
+	callOpts, cancel := r.getCallOpts(ctx)
+	address, err := r.ChallengeManager(callOpts)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("error getting challenge manager address: %w", err)
+	}
+	con, err := challenge_legacy_gen.NewChallengeManager(address, &retryingClient{RollupWatcherL1Interface: r.client, policy: r.retryPolicy})
+	if err != nil {
+		return nil, err
+	}
+	r.challengeManagerCon.Store(con)
+	return con, nil
+}
+
+// challengeState returns the state of a challenge, or nil if it has been resolved.
+func (r *RollupWatcher) challengeState(ctx context.Context, con *challenge_legacy_gen.ChallengeManager, challengeIndex uint64) (*ChallengeState, error) {
+	callOpts, cancel := r.getCallOpts(ctx)
+	defer cancel()
+	challenge, err := con.Challenges(callOpts, new(big.Int).SetUint64(challengeIndex))
+	if err != nil {
+		return nil, fmt.Errorf("error getting challenge %v: %w", challengeIndex, err)
+	}
+	if challenge.Mode == challengeModeNone {
+		return nil, nil
+	}
+	return &ChallengeState{
+		Mode:               challenge.Mode,
+		CurrentResponder:   challenge.Current.Addr,
+		CurrentTimeLeft:    challenge.Current.TimeLeft,
+		NextResponder:      challenge.Next.Addr,
+		NextTimeLeft:       challenge.Next.TimeLeft,
+		LastMoveTimestamp:  challenge.LastMoveTimestamp,
+		WasmModuleRoot:     challenge.WasmModuleRoot,
+		ChallengeStateHash: challenge.ChallengeStateHash,
+		MaxInboxMessages:   challenge.MaxInboxMessages,
+	}, nil
+}
+
+// ActiveChallenges returns every challenge on the rollup that hasn't been resolved yet, in the order they started.
+func (r *RollupWatcher) ActiveChallenges(ctx context.Context) ([]ChallengeInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	events, err := r.recentChallengesStarted(ctx)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	con, err := r.challengeManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return activeChallenges(events, func(challengeIndex uint64) (bool, error) {
+		state, err := r.challengeState(ctx, con, challengeIndex)
+		return state != nil, err
+	})
+}
+
+// recentChallengesStarted returns the RollupChallengeStarted events of the challenges that may still be active.
+// Active challenges are over unconfirmed nodes, so only challenges started after the latest confirmed node was
+// created are scanned for, in chunks of the default log query range size.
+func (r *RollupWatcher) recentChallengesStarted(ctx context.Context) ([]*rollup_legacy_gen.RollupUserLogicRollupChallengeStarted, error) {
+	latestConfirmedCreated, err := r.LatestConfirmedCreationBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting parent chain head: %w", err)
+	}
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{r.address},
+		Topics:    [][]common.Hash{{challengeCreatedID}},
+	}
+	logs, err := r.filterLogsInRange(ctx, query, new(big.Int).SetUint64(latestConfirmedCreated), head.Number, r.defaultLogQueryRangeSize, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*rollup_legacy_gen.RollupUserLogicRollupChallengeStarted, 0, len(logs))
+	for _, ethLog := range logs {
+		ev, err := r.ParseRollupChallengeStarted(ethLog)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// activeChallenges returns the challenges started by events that isActive reports as still active,
+// keeping the events' order and skipping repeated events for the same challenge.
+func activeChallenges(events []*rollup_legacy_gen.RollupUserLogicRollupChallengeStarted, isActive func(challengeIndex uint64) (bool, error)) ([]ChallengeInfo, error) {
+	var challenges []ChallengeInfo
+	seen := make(map[uint64]struct{}, len(events))
+	for _, ev := range events {
+		if _, ok := seen[ev.ChallengeIndex]; ok {
+			continue
+		}
+		seen[ev.ChallengeIndex] = struct{}{}
+		active, err := isActive(ev.ChallengeIndex)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			continue
+		}
+		challenges = append(challenges, ChallengeInfo{
+			Index:          ev.ChallengeIndex,
+			Asserter:       ev.Asserter,
+			Challenger:     ev.Challenger,
+			ChallengedNode: ev.ChallengedNode,
+		})
+	}
+	return challenges, nil
+}
+
+// StakerChallenge returns the challenge the staker is currently in, including its opponent and the challenge's
+// current state, or nil if the staker isn't staked or isn't in an active challenge.
+func (r *RollupWatcher) StakerChallenge(ctx context.Context, staker common.Address) (*ChallengeInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	stakerInfo, err := r.StakerInfo(ctx, staker)
+	if err != nil {
+		return nil, err
+	}
+	if stakerInfo == nil || stakerInfo.CurrentChallenge == nil {
+		return nil, nil
+	}
+	challengeIndex := *stakerInfo.CurrentChallenge
+	con, err := r.challengeManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := r.challengeState(ctx, con, challengeIndex)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	started, err := r.lookupChallengeStarted(ctx, challengeIndex)
+	if err != nil {
+		return nil, err
+	}
+	info := &ChallengeInfo{
+		Index:          challengeIndex,
+		Asserter:       started.Asserter,
+		Challenger:     started.Challenger,
+		ChallengedNode: started.ChallengedNode,
+		Opponent:       started.Asserter,
+		State:          state,
+	}
+	if staker == started.Asserter {
+		info.Opponent = started.Challenger
+	}
+	return info, nil
+}
+
+// lookupChallengeStarted finds the RollupChallengeStarted event of an active challenge.
+func (r *RollupWatcher) lookupChallengeStarted(ctx context.Context, challengeIndex uint64) (*rollup_legacy_gen.RollupUserLogicRollupChallengeStarted, error) {
+	events, err := r.recentChallengesStarted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range events {
+		if ev.ChallengeIndex == challengeIndex {
+			return ev, nil
+		}
+	}
+	return nil, fmt.Errorf("couldn't find the start of challenge %v", challengeIndex)
+}
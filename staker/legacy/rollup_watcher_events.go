@@ -0,0 +1,150 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+
+	"github.com/offchainlabs/nitro/solgen/go/rollup_legacy_gen"
+)
+
+type NodeConfirmedInfo struct {
+	NodeNum                   uint64
+	BlockHash                 common.Hash
+	SendRoot                  common.Hash
+	L1BlockConfirmed          uint64
+	ParentChainBlockConfirmed uint64
+}
+
+func (r *RollupWatcher) nodeConfirmedInfoFromEvent(ctx context.Context, ev *rollup_legacy_gen.RollupUserLogicNodeConfirmed) (*NodeConfirmedInfo, error) {
+	l1BlockConfirmed, err := r.correspondingL1BlockNumber(ctx, ev.Raw.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeConfirmedInfo{
+		NodeNum:                   ev.NodeNum,
+		BlockHash:                 ev.BlockHash,
+		SendRoot:                  ev.SendRoot,
+		L1BlockConfirmed:          l1BlockConfirmed,
+		ParentChainBlockConfirmed: ev.Raw.BlockNumber,
+	}, nil
+}
+
+// filterNodeLogsSinceCreation queries the logs with the given event ID and node number from
+// the node's creation block up to the current parent chain head.
+func (r *RollupWatcher) filterNodeLogsSinceCreation(ctx context.Context, eventID common.Hash, nodeNum uint64) ([]types.Log, error) {
+	createdAtBlock, err := r.getNodeCreationBlock(ctx, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting parent chain head: %w", err)
+	}
+	var query = ethereum.FilterQuery{
+		Addresses: []common.Address{r.address},
+		Topics:    [][]common.Hash{{eventID}, {uint64ToIndex(nodeNum)}},
+	}
+	return r.filterLogsInRange(ctx, query, createdAtBlock, head.Number, r.defaultLogQueryRangeSize, 0, nil)
+}
+
+// LookupNodeConfirmed returns the confirmation of a node, or nil if it hasn't been confirmed (yet).
+func (r *RollupWatcher) LookupNodeConfirmed(ctx context.Context, nodeNum uint64) (*NodeConfirmedInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	logs, err := r.filterNodeLogsSinceCreation(ctx, nodeConfirmedID, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	if len(logs) > 1 {
+		return nil, fmt.Errorf("found multiple confirmations of node %v", nodeNum)
+	}
+	ev, err := r.ParseNodeConfirmed(logs[0])
+	if err != nil {
+		return nil, err
+	}
+	return r.nodeConfirmedInfoFromEvent(ctx, ev)
+}
+
+// LookupNodeRejected reports whether a node has been rejected and, if so, the L1 block it was rejected in.
+// A node that hasn't been rejected returns false with a nil block and no error.
+func (r *RollupWatcher) LookupNodeRejected(ctx context.Context, nodeNum uint64) (bool, *big.Int, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer release()
+	logs, err := r.filterNodeLogsSinceCreation(ctx, nodeRejectedID, nodeNum)
+	if err != nil {
+		return false, nil, err
+	}
+	if len(logs) == 0 {
+		return false, nil, nil
+	}
+	if len(logs) > 1 {
+		return false, nil, fmt.Errorf("found multiple rejections of node %v", nodeNum)
+	}
+	ev, err := r.ParseNodeRejected(logs[0])
+	if err != nil {
+		return false, nil, err
+	}
+	l1BlockRejected, err := r.correspondingL1BlockNumber(ctx, ev.Raw.BlockNumber)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, new(big.Int).SetUint64(l1BlockRejected), nil
+}
+
+// SubscribeNodeConfirmed sends node confirmations to sink as they happen, optionally only for the given nodes.
+// It requires a client that supports log subscriptions. The subscription ends when ctx is done,
+// when it's unsubscribed, or when the watcher is closed.
+func (r *RollupWatcher) SubscribeNodeConfirmed(ctx context.Context, sink chan<- *NodeConfirmedInfo, nodeNums ...uint64) (event.Subscription, error) {
+	if r.closed.Load() {
+		return nil, fmt.Errorf("rollup watcher for %v: %w", r.address, ErrClosed)
+	}
+	events := make(chan *rollup_legacy_gen.RollupUserLogicNodeConfirmed)
+	watchSub, err := r.WatchNodeConfirmed(&bind.WatchOpts{Context: ctx}, events, nodeNums)
+	if err != nil {
+		return nil, err
+	}
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		defer watchSub.Unsubscribe()
+		for {
+			select {
+			case ev := <-events:
+				info, err := r.nodeConfirmedInfoFromEvent(ctx, ev)
+				if err != nil {
+					return err
+				}
+				select {
+				case sink <- info:
+				case err := <-watchSub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-watchSub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
+	r.trackSubscription(sub)
+	return sub, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// WithFallbackClients adds clients that reads fail over to, in order, when the current client fails with a transport error.
+// The client passed to NewRollupWatcher stays the primary and is used until it errors.
+func WithFallbackClients(clients ...RollupWatcherL1Interface) RollupWatcherOption {
+	return func(r *RollupWatcher) {
+		r.fallbackClients = append(r.fallbackClients, clients...)
+	}
+}
+
+// failoverClient sends eth_call, eth_getCode, eth_getBlockByNumber and eth_getLogs requests to the current client,
+// switching to the next one in order when a request fails with a transport error.
+// The current client stays sticky until it errors. Other requests always go to the primary client.
+type failoverClient struct {
+	RollupWatcherL1Interface
+	clients []RollupWatcherL1Interface
+	current atomic.Int32
+}
+
+func newFailoverClient(clients []RollupWatcherL1Interface) *failoverClient {
+	return &failoverClient{
+		RollupWatcherL1Interface: clients[0],
+		clients:                  clients,
+	}
+}
+
+// failoverCall tries each client once, starting with the current one, until one doesn't fail with a transport error.
+func failoverCall[T any](c *failoverClient, name string, call func(RollupWatcherL1Interface) (T, error)) (T, error) {
+	// #nosec G115
+	numClients := int32(len(c.clients))
+	start := c.current.Load()
+	var res T
+	var err error
+	for i := int32(0); i < numClients; i++ {
+		index := (start + i) % numClients
+		res, err = call(c.clients[index])
+		if err == nil || !isRetryableError(err) {
+			return res, err
+		}
+		next := (index + 1) % numClients
+		if i+1 < numClients && c.current.CompareAndSwap(index, next) {
+			log.Warn("rollup watcher RPC client failed, switching to next client", "request", name, "from", index, "to", next, "err", err)
+		}
+	}
+	return res, err
+}
+
+func (c *failoverClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return failoverCall(c, "eth_getCode", func(client RollupWatcherL1Interface) ([]byte, error) {
+		return client.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (c *failoverClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return failoverCall(c, "eth_call", func(client RollupWatcherL1Interface) ([]byte, error) {
+		return client.CallContract(ctx, call, blockNumber)
+	})
+}
+
+func (c *failoverClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return failoverCall(c, "eth_getBlockByNumber", func(client RollupWatcherL1Interface) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+func (c *failoverClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return failoverCall(c, "eth_getLogs", func(client RollupWatcherL1Interface) ([]types.Log, error) {
+		return client.FilterLogs(ctx, q)
+	})
+}
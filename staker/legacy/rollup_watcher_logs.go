@@ -0,0 +1,329 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// logQueryBucket is a bucket of eth_getLogs block range sizes, with its own duration histogram.
+type logQueryBucket struct {
+	maxBlocks uint64
+	duration  metrics.Histogram
+}
+
+func newLogQueryBucket(maxBlocks uint64, name string) logQueryBucket {
+	return logQueryBucket{
+		maxBlocks: maxBlocks,
+		duration:  metrics.NewRegisteredHistogram("arb/staker/rollupwatcher/getlogs/duration/"+name, nil, metrics.NewBoundedHistogramSample()),
+	}
+}
+
+var (
+	// logQueryBuckets are in increasing order of maxBlocks.
+	logQueryBuckets = []logQueryBucket{
+		newLogQueryBucket(1, "1"),
+		newLogQueryBucket(100, "100"),
+		newLogQueryBucket(1_000, "1000"),
+		newLogQueryBucket(10_000, "10000"),
+		newLogQueryBucket(100_000, "100000"),
+	}
+	// unboundedLogQueryBucket covers larger ranges, and queries with an open ended range.
+	unboundedLogQueryBucket = newLogQueryBucket(0, "unbounded")
+
+	logQueryRangeSizeHistogram = metrics.NewRegisteredHistogram("arb/staker/rollupwatcher/getlogs/rangesize", nil, metrics.NewBoundedHistogramSample())
+	logQueryShrunkCounter      = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/getlogs/shrunk", nil)
+)
+
+// recordLogQuery records how long an eth_getLogs query took, in the histogram of its range size's bucket.
+func recordLogQuery(query ethereum.FilterQuery, duration time.Duration) {
+	bucket := unboundedLogQueryBucket
+	if query.FromBlock != nil && query.ToBlock != nil && query.ToBlock.Cmp(query.FromBlock) >= 0 {
+		blocks := new(big.Int).Sub(query.ToBlock, query.FromBlock).Uint64() + 1
+		// #nosec G115
+		logQueryRangeSizeHistogram.Update(int64(blocks))
+		for _, b := range logQueryBuckets {
+			if blocks <= b.maxBlocks {
+				bucket = b
+				break
+			}
+		}
+	}
+	bucket.duration.Update(duration.Nanoseconds())
+}
+
+type blockRange struct {
+	from *big.Int
+	to   *big.Int
+}
+
+// splitBlockRange breaks fromBlock..toBlock (inclusive) into consecutive ranges each ending at most
+// rangeSize blocks after it starts. A rangeSize of 0 queries everything in one range.
+func splitBlockRange(fromBlock *big.Int, toBlock *big.Int, rangeSize uint64) []blockRange {
+	var ranges []blockRange
+	for toBlock.Cmp(fromBlock) >= 0 {
+		end := toBlock
+		if rangeSize != 0 {
+			end = new(big.Int).Add(fromBlock, new(big.Int).SetUint64(rangeSize))
+		}
+		if end.Cmp(toBlock) > 0 {
+			end = toBlock
+		}
+		ranges = append(ranges, blockRange{from: fromBlock, to: end})
+		fromBlock = new(big.Int).Add(end, big.NewInt(1))
+	}
+	return ranges
+}
+
+// logLimitError is returned by filterLogsInRange when the query matched more logs than it was allowed to collect.
+type logLimitError struct {
+	count uint64
+	limit uint64
+}
+
+func (e *logLimitError) Error() string {
+	return fmt.Sprintf("log query matched at least %v logs, more than the limit of %v", e.count, e.limit)
+}
+
+// ProgressFunc is told that a range scan from fromBlock to toBlock has fetched every block up to currentBlock.
+type ProgressFunc func(currentBlock *big.Int, fromBlock *big.Int, toBlock *big.Int)
+
+// filterLogsInRange runs query over fromBlock..toBlock (inclusive), split into segments of at most
+// rangeSize+1 blocks to stay under provider eth_getLogs limits, and returns the logs in block order.
+// Segments are fetched concurrently up to the watcher's children lookup concurrency.
+// If maxLogs isn't 0, the scan stops with a *logLimitError as soon as more logs than that were fetched.
+// If progress isn't nil, it's called whenever the fetched blocks extend further from fromBlock without gaps.
+func (r *RollupWatcher) filterLogsInRange(ctx context.Context, query ethereum.FilterQuery, fromBlock *big.Int, toBlock *big.Int, rangeSize uint64, maxLogs uint64, progress ProgressFunc) ([]types.Log, error) {
+	segments := splitBlockRange(fromBlock, toBlock, rangeSize)
+	segmentLogs := make([][]types.Log, len(segments))
+	// progressMutex serializes progress reports so currentBlock only ever increases
+	var progressMutex sync.Mutex
+	segmentDone := make([]bool, len(segments))
+	nextPending := 0
+	reportDone := func(i int) {
+		if progress == nil {
+			return
+		}
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		segmentDone[i] = true
+		if i != nextPending {
+			return
+		}
+		for nextPending < len(segments) && segmentDone[nextPending] {
+			nextPending++
+		}
+		progress(new(big.Int).Set(segments[nextPending-1].to), fromBlock, toBlock)
+	}
+	// shared between segments so every segment benefits from a range limit discovered by another
+	var maxRangeSize atomic.Uint64
+	var totalLogs atomic.Uint64
+	var slots *semaphore.Weighted
+	if r.childrenLookupConcurrency > 0 {
+		slots = semaphore.NewWeighted(int64(r.childrenLookupConcurrency))
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	for i, segment := range segments {
+		// stop promptly between segments, or while waiting for a slot, if the lookup was cancelled or a segment failed
+		if slots != nil {
+			if err := slots.Acquire(gctx, 1); err != nil {
+				break
+			}
+		} else if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			if slots != nil {
+				defer slots.Release(1)
+			}
+			logs, err := r.filterLogsAdaptive(gctx, query, segment.from, segment.to, &maxRangeSize)
+			if err != nil {
+				return err
+			}
+			if count := totalLogs.Add(uint64(len(logs))); maxLogs != 0 && count > maxLogs {
+				return &logLimitError{count: count, limit: maxLogs}
+			}
+			segmentLogs[i] = logs
+			reportDone(i)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// segments are in block order, so concatenating them keeps the logs in the order they were emitted
+	var logs []types.Log
+	for _, segment := range segmentLogs {
+		logs = append(logs, segment...)
+	}
+	if r.checkSegmentBoundaries {
+		missed, err := r.countMissedBoundaryLogs(ctx, query, segments, logs)
+		if err != nil {
+			log.Warn("failed to check eth_getLogs segment boundaries", "fromBlock", fromBlock, "toBlock", toBlock, "err", err)
+		} else if missed > 0 {
+			log.Error("eth_getLogs segments missed logs at their boundaries, range splitting is broken", "fromBlock", fromBlock, "toBlock", toBlock, "rangeSize", rangeSize, "missed", missed)
+		}
+	}
+	return logs, nil
+}
+
+// countMissedBoundaryLogs re-queries each block on either side of a boundary between segments on its own
+// and returns how many more logs that finds than logs has for those blocks.
+func (r *RollupWatcher) countMissedBoundaryLogs(ctx context.Context, query ethereum.FilterQuery, segments []blockRange, logs []types.Log) (int, error) {
+	perBlock := make(map[uint64]int)
+	for _, ethLog := range logs {
+		perBlock[ethLog.BlockNumber]++
+	}
+	missed := 0
+	for i := 1; i < len(segments); i++ {
+		for _, block := range []*big.Int{segments[i-1].to, segments[i].from} {
+			query.FromBlock = block
+			query.ToBlock = block
+			blockLogs, err := r.filterLogs(ctx, query)
+			if err != nil {
+				return 0, err
+			}
+			if extra := len(blockLogs) - perBlock[block.Uint64()]; extra > 0 {
+				missed += extra
+			}
+		}
+	}
+	return missed, nil
+}
+
+// Error messages providers use to reject an eth_getLogs query that covers too much.
+var logQueryTooLargeSubstrings = []string{
+	"query returned more than",
+	"response size exceeded",
+	"response size should not greater than",
+	"exceed maximum block range",
+	"block range is too large",
+	"block range too large",
+	"too many results",
+	"range is too large",
+	"block range limit",
+	"is limited to a",
+}
+
+// Patterns of the block range some providers suggest when rejecting an eth_getLogs query as too large,
+// either as an explicit range of hex block numbers or as a maximum number of blocks.
+var (
+	suggestedLogQueryRangePattern = regexp.MustCompile(`\[(0x[0-9a-f]+),\s*(0x[0-9a-f]+)\]`)
+	suggestedLogQuerySizePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`up to a (\d[\d,]*k?) block range`),
+		regexp.MustCompile(`is limited to a (\d[\d,]*k?) (?:block )?range`),
+		regexp.MustCompile(`exceed maximum block range:?\s*(\d[\d,]*k?)`),
+		regexp.MustCompile(`block range limit(?: exceeded)?[:\s(]*(\d[\d,]*k?)`),
+		regexp.MustCompile(`max(?:imum)?(?: block)? range(?: is| of)?:?\s*(\d[\d,]*k?)`),
+	}
+)
+
+func isLogQueryTooLargeError(err error) bool {
+	errString := strings.ToLower(err.Error())
+	for _, substring := range logQueryTooLargeSubstrings {
+		if strings.Contains(errString, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestedLogQueryRangeSize returns the number of blocks a provider suggested querying at once in err,
+// which rejected an eth_getLogs query as too large, or 0 if it didn't suggest any.
+func suggestedLogQueryRangeSize(err error) uint64 {
+	errString := strings.ToLower(err.Error())
+	if match := suggestedLogQueryRangePattern.FindStringSubmatch(errString); match != nil {
+		from, fromErr := strconv.ParseUint(match[1][2:], 16, 64)
+		to, toErr := strconv.ParseUint(match[2][2:], 16, 64)
+		if fromErr == nil && toErr == nil && to >= from {
+			return to - from + 1
+		}
+	}
+	for _, pattern := range suggestedLogQuerySizePatterns {
+		match := pattern.FindStringSubmatch(errString)
+		if match == nil {
+			continue
+		}
+		number := strings.ReplaceAll(match[1], ",", "")
+		multiplier := uint64(1)
+		if strings.HasSuffix(number, "k") {
+			number = strings.TrimSuffix(number, "k")
+			multiplier = 1000
+		}
+		size, err := strconv.ParseUint(number, 10, 64)
+		if err == nil && size > 0 {
+			return size * multiplier
+		}
+	}
+	return 0
+}
+
+// filterLogsAdaptive queries logs in fromBlock..toBlock (inclusive), shrinking the queried range whenever
+// the provider rejects it as too large, down to a single block. The range jumps straight to the size the
+// provider suggested in its error if it gave one, and is halved otherwise. The largest accepted range, in
+// blocks, is kept in maxRangeSize (0 meaning unlimited) so later queries sharing it start from a size that works.
+func (r *RollupWatcher) filterLogsAdaptive(ctx context.Context, query ethereum.FilterQuery, fromBlock *big.Int, toBlock *big.Int, maxRangeSize *atomic.Uint64) ([]types.Log, error) {
+	var logs []types.Log
+	for fromBlock.Cmp(toBlock) <= 0 {
+		end := toBlock
+		if size := maxRangeSize.Load(); size != 0 {
+			end = new(big.Int).Add(fromBlock, new(big.Int).SetUint64(size-1))
+			if end.Cmp(toBlock) > 0 {
+				end = toBlock
+			}
+		}
+		query.FromBlock = fromBlock
+		query.ToBlock = end
+		segment, err := r.filterLogs(ctx, query)
+		if err != nil {
+			blocks := new(big.Int).Sub(end, fromBlock).Uint64() + 1
+			if blocks <= 1 || !isLogQueryTooLargeError(err) {
+				return nil, err
+			}
+			size := blocks / 2
+			if suggested := suggestedLogQueryRangeSize(err); suggested != 0 && suggested < blocks {
+				size = suggested
+			}
+			shrinkRangeSize(maxRangeSize, size)
+			logQueryShrunkCounter.Inc(1)
+			log.Warn("eth_getLogs range rejected by provider, retrying with a smaller range", "fromBlock", fromBlock, "toBlock", end, "rangeSize", maxRangeSize.Load(), "err", err)
+			continue
+		}
+		logs = append(logs, segment...)
+		fromBlock = new(big.Int).Add(end, big.NewInt(1))
+	}
+	return logs, nil
+}
+
+// shrinkRangeSize lowers maxRangeSize to size unless it's already at most that.
+func shrinkRangeSize(maxRangeSize *atomic.Uint64, size uint64) {
+	for {
+		current := maxRangeSize.Load()
+		if current != 0 && current <= size {
+			return
+		}
+		if maxRangeSize.CompareAndSwap(current, size) {
+			return
+		}
+	}
+}
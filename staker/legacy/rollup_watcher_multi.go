@@ -0,0 +1,258 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MultiRollupWatcher watches several rollups over a single shared parent chain client,
+// fanning lookups out to a RollupWatcher per rollup address.
+type MultiRollupWatcher struct {
+	client    RollupWatcherL1Interface
+	addresses []common.Address
+	watchers  map[common.Address]*RollupWatcher
+}
+
+// NewMultiRollupWatcher creates a RollupWatcher for each rollup address, all sharing client and configured by opts.
+// Fallback clients given with WithFallbackClients are wrapped around client once and shared too, so every watcher
+// fails over together. Other options, like the caches and concurrency limits, apply to each watcher separately,
+// as node numbers and their data are per rollup.
+func NewMultiRollupWatcher(addresses []common.Address, client RollupWatcherL1Interface, callOpts bind.CallOpts, opts ...RollupWatcherOption) (*MultiRollupWatcher, error) {
+	var template RollupWatcher
+	for _, opt := range opts {
+		opt(&template)
+	}
+	if len(template.fallbackClients) > 0 {
+		client = newFailoverClient(append([]RollupWatcherL1Interface{client}, template.fallbackClients...))
+		// the watchers use the shared failover client rather than each wrapping client in one of their own
+		opts = append(append([]RollupWatcherOption(nil), opts...), func(r *RollupWatcher) {
+			r.fallbackClients = nil
+		})
+	}
+	m := &MultiRollupWatcher{
+		client:   client,
+		watchers: make(map[common.Address]*RollupWatcher, len(addresses)),
+	}
+	for _, address := range addresses {
+		if _, ok := m.watchers[address]; ok {
+			return nil, fmt.Errorf("rollup %v given more than once", address)
+		}
+		watcher, err := NewRollupWatcher(address, client, callOpts, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("error creating watcher for rollup %v: %w", address, err)
+		}
+		m.watchers[address] = watcher
+		m.addresses = append(m.addresses, address)
+	}
+	return m, nil
+}
+
+// Addresses returns the watched rollup addresses in the order they were given.
+func (m *MultiRollupWatcher) Addresses() []common.Address {
+	return append([]common.Address(nil), m.addresses...)
+}
+
+// Client returns the parent chain client shared by the watchers.
+func (m *MultiRollupWatcher) Client() RollupWatcherL1Interface {
+	return m.client
+}
+
+// Watcher returns the watcher of a single rollup, if it's watched.
+func (m *MultiRollupWatcher) Watcher(address common.Address) (*RollupWatcher, bool) {
+	watcher, ok := m.watchers[address]
+	return watcher, ok
+}
+
+func (m *MultiRollupWatcher) watcher(address common.Address) (*RollupWatcher, error) {
+	watcher, ok := m.watchers[address]
+	if !ok {
+		return nil, fmt.Errorf("rollup %v isn't watched", address)
+	}
+	return watcher, nil
+}
+
+// Initialize initializes every rollup's watcher concurrently.
+func (m *MultiRollupWatcher) Initialize(ctx context.Context) error {
+	g, gctx := errgroup.WithContext(ctx)
+	for _, address := range m.addresses {
+		watcher := m.watchers[address]
+		g.Go(func() error {
+			if err := watcher.Initialize(gctx); err != nil {
+				return fmt.Errorf("error initializing watcher for rollup %v: %w", address, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// Close closes every rollup's watcher.
+func (m *MultiRollupWatcher) Close() error {
+	var errs []error
+	for _, address := range m.addresses {
+		errs = append(errs, m.watchers[address].Close())
+	}
+	return errors.Join(errs...)
+}
+
+// StakerInfo looks up a staker on every watched rollup concurrently.
+// Like RollupWatcher.StakerInfo, rollups the address isn't staked on map to nil.
+func (m *MultiRollupWatcher) StakerInfo(ctx context.Context, staker common.Address) (map[common.Address]*StakerInfo, error) {
+	infos := make(map[common.Address]*StakerInfo, len(m.addresses))
+	var infosMutex sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	for _, address := range m.addresses {
+		watcher := m.watchers[address]
+		g.Go(func() error {
+			info, err := watcher.StakerInfo(gctx, staker)
+			if err != nil {
+				return fmt.Errorf("error getting staker %v on rollup %v: %w", staker, address, err)
+			}
+			infosMutex.Lock()
+			infos[address] = info
+			infosMutex.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+type multiNodeLookup struct {
+	address        common.Address
+	watcher        *RollupWatcher
+	nodeNum        uint64
+	createdAtBlock *big.Int
+}
+
+// LookupNode looks up one node on each of the given rollups, keyed by rollup address.
+// Nodes created in the same parent chain block share a single eth_getLogs query across rollups.
+func (m *MultiRollupWatcher) LookupNode(ctx context.Context, nodeNums map[common.Address]uint64) (map[common.Address]*NodeInfo, error) {
+	for address := range nodeNums {
+		if _, err := m.watcher(address); err != nil {
+			return nil, err
+		}
+	}
+	lookups := make([]*multiNodeLookup, 0, len(nodeNums))
+	var releases []context.CancelFunc
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+	for _, address := range m.addresses {
+		nodeNum, ok := nodeNums[address]
+		if !ok {
+			continue
+		}
+		watcher := m.watchers[address]
+		// bind ctx to each involved watcher so closing any of them cancels the lookup
+		scopedCtx, release, err := watcher.scopeContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+		ctx = scopedCtx
+		lookups = append(lookups, &multiNodeLookup{address: address, watcher: watcher, nodeNum: nodeNum})
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	for _, lookup := range lookups {
+		g.Go(func() error {
+			createdAtBlock, err := lookup.watcher.getNodeCreationBlock(gctx, lookup.nodeNum)
+			if err != nil {
+				return fmt.Errorf("error getting creation block of node %v on rollup %v: %w", lookup.nodeNum, lookup.address, err)
+			}
+			if err := lookup.watcher.checkNodeFinal(gctx, lookup.nodeNum, createdAtBlock); err != nil {
+				return fmt.Errorf("error looking up node %v on rollup %v: %w", lookup.nodeNum, lookup.address, err)
+			}
+			lookup.createdAtBlock = createdAtBlock
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	byBlock := make(map[uint64][]*multiNodeLookup)
+	for _, lookup := range lookups {
+		block := lookup.createdAtBlock.Uint64()
+		byBlock[block] = append(byBlock[block], lookup)
+	}
+	infos := make(map[common.Address]*NodeInfo, len(lookups))
+	var infosMutex sync.Mutex
+	g, gctx = errgroup.WithContext(ctx)
+	for _, group := range byBlock {
+		g.Go(func() error {
+			groupInfos, err := m.lookupNodesCreatedInBlock(gctx, group)
+			if err != nil {
+				return err
+			}
+			infosMutex.Lock()
+			defer infosMutex.Unlock()
+			for address, info := range groupInfos {
+				infos[address] = info
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// lookupNodesCreatedInBlock fetches the NodeCreated logs of nodes on different rollups that were all created
+// in the same parent chain block with one query, then builds each node's info from its own rollup's logs.
+func (m *MultiRollupWatcher) lookupNodesCreatedInBlock(ctx context.Context, lookups []*multiNodeLookup) (map[common.Address]*NodeInfo, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: lookups[0].createdAtBlock,
+		ToBlock:   lookups[0].createdAtBlock,
+		Topics:    [][]common.Hash{{nodeCreatedID}, nil},
+	}
+	for _, lookup := range lookups {
+		query.Addresses = append(query.Addresses, lookup.address)
+		query.Topics[1] = append(query.Topics[1], uint64ToIndex(lookup.nodeNum))
+	}
+	// every watcher shares the client, so any of them can run the query
+	logs, err := lookups[0].watcher.filterLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[common.Address]*NodeInfo, len(lookups))
+	for _, lookup := range lookups {
+		nodeNumTopic := uint64ToIndex(lookup.nodeNum)
+		var nodeLogs []types.Log
+		for _, ethLog := range logs {
+			if ethLog.Address == lookup.address && len(ethLog.Topics) > 1 && ethLog.Topics[1] == nodeNumTopic {
+				nodeLogs = append(nodeLogs, ethLog)
+			}
+		}
+		var info *NodeInfo
+		if len(nodeLogs) == 0 && lookup.watcher.reprobeStoredL3MethodSupport(lookup.nodeNum) {
+			info, err = lookup.watcher.LookupNode(ctx, lookup.nodeNum)
+		} else {
+			info, err = lookup.watcher.nodeInfoFromCreationLogs(ctx, lookup.nodeNum, nodeLogs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error looking up node %v on rollup %v: %w", lookup.nodeNum, lookup.address, err)
+		}
+		infos[lookup.address] = info
+	}
+	return infos, nil
+}
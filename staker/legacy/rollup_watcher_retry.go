@@ -0,0 +1,247 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/util/headerreader"
+)
+
+var rateLimitedCounter = metrics.NewRegisteredCounter("arb/staker/rollupwatcher/ratelimited", nil)
+
+// RetryPolicy controls how the watcher retries RPC requests that failed with a transient error.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Jitter randomizes each delay by up to this fraction of it in either direction.
+	Jitter float64
+	// RateLimitMaxAttempts is the total number of attempts for requests the provider rate limited.
+	// Zero falls back to MaxAttempts.
+	RateLimitMaxAttempts int
+	// RateLimitBaseDelay and RateLimitMaxDelay bound the backoff after a rate limited request
+	// whose response doesn't say when to retry.
+	RateLimitBaseDelay time.Duration
+	RateLimitMaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy makes a single attempt, matching the watcher's original behavior,
+// except for rate limited requests which are retried with a longer backoff.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          1,
+	BaseDelay:            time.Second,
+	MaxDelay:             30 * time.Second,
+	Jitter:               0.2,
+	RateLimitMaxAttempts: 5,
+	RateLimitBaseDelay:   5 * time.Second,
+	RateLimitMaxDelay:    time.Minute,
+}
+
+// delay returns how long to wait after the given (zero-based) failed attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.backoff(p.BaseDelay, p.MaxDelay, attempt)
+}
+
+// rateLimitDelay returns how long to wait after the given (zero-based) rate limited attempt,
+// honoring the provider's requested delay if the error includes one.
+func (p RetryPolicy) rateLimitDelay(attempt int, err error) time.Duration {
+	if delay, ok := retryAfter(err); ok {
+		if p.RateLimitMaxDelay > 0 && delay > p.RateLimitMaxDelay {
+			return p.RateLimitMaxDelay
+		}
+		return delay
+	}
+	return p.backoff(p.RateLimitBaseDelay, p.RateLimitMaxDelay, attempt)
+}
+
+func (p RetryPolicy) rateLimitMaxAttempts() int {
+	if p.RateLimitMaxAttempts == 0 {
+		return p.MaxAttempts
+	}
+	return p.RateLimitMaxAttempts
+}
+
+func (p RetryPolicy) backoff(baseDelay time.Duration, maxDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay
+	for i := 0; i < attempt && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	if p.Jitter > 0 {
+		// #nosec G404
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+var transientErrorSubstrings = []string{
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"unexpected eof",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+	"internal server error",
+}
+
+// isRetryableError reports whether err looks like a transient transport failure.
+// Deterministic failures such as reverted calls are never retried.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if headerreader.IsExecutionReverted(err) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 408
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	errString := strings.ToLower(err.Error())
+	for _, substring := range transientErrorSubstrings {
+		if strings.Contains(errString, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+var rateLimitErrorSubstrings = []string{
+	"rate limit",
+	"too many requests",
+	"exceeded the quota",
+	"request limit",
+	"compute units per second",
+	"capacity limit",
+}
+
+// isRateLimitError reports whether err is a provider rejecting the request for being over its rate limit.
+func isRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	errString := strings.ToLower(err.Error())
+	for _, substring := range rateLimitErrorSubstrings {
+		if strings.Contains(errString, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// Providers pass Retry-After along in the error body in various forms, e.g. "retry after 2s" or "try again in 500ms".
+var retryAfterRegexp = regexp.MustCompile(`(?i)(?:retry[- _]?after|try again in)["':= ]*(\d+(?:\.\d+)?) ?(ms|s|sec|seconds)?\b`)
+
+// retryAfter extracts the delay a rate limited response asked for, in seconds unless stated otherwise.
+func retryAfter(err error) (time.Duration, bool) {
+	match := retryAfterRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+	value, parseErr := strconv.ParseFloat(match[1], 64)
+	if parseErr != nil {
+		return 0, false
+	}
+	unit := time.Second
+	if strings.EqualFold(match[2], "ms") {
+		unit = time.Millisecond
+	}
+	return time.Duration(value * float64(unit)), true
+}
+
+// retryCall runs call until it succeeds, fails with a non-retryable error, or the policy runs out of attempts.
+// Rate limited requests are retried separately, with their own attempt limit and a longer backoff.
+func retryCall[T any](ctx context.Context, policy RetryPolicy, name string, call func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := call()
+		if err == nil || ctx.Err() != nil {
+			return res, err
+		}
+		var delay time.Duration
+		if isRateLimitError(err) {
+			rateLimitedCounter.Inc(1)
+			if attempt+1 >= policy.rateLimitMaxAttempts() {
+				return res, err
+			}
+			delay = policy.rateLimitDelay(attempt, err)
+			log.Warn("rollup watcher RPC request rate limited, backing off", "request", name, "attempt", attempt+1, "delay", delay, "err", err)
+		} else if attempt+1 < policy.MaxAttempts && isRetryableError(err) {
+			delay = policy.delay(attempt)
+			log.Warn("rollup watcher RPC request failed, retrying", "request", name, "attempt", attempt+1, "delay", delay, "err", err)
+		} else {
+			return res, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var empty T
+			return empty, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *RollupWatcher) filterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return retryCall(ctx, r.retryPolicy, "eth_getLogs", func() ([]types.Log, error) {
+		start := time.Now()
+		logs, err := r.client.FilterLogs(ctx, query)
+		recordLogQuery(query, time.Since(start))
+		return logs, err
+	})
+}
+
+// retryingClient applies the watcher's retry policy to the eth_calls made through the contract binding.
+type retryingClient struct {
+	RollupWatcherL1Interface
+	policy RetryPolicy
+}
+
+func (c *retryingClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return retryCall(ctx, c.policy, "eth_getCode", func() ([]byte, error) {
+		return c.RollupWatcherL1Interface.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (c *retryingClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return retryCall(ctx, c.policy, "eth_call", func() ([]byte, error) {
+		return c.RollupWatcherL1Interface.CallContract(ctx, call, blockNumber)
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// searchFirst returns the smallest n in [lo, hi] for which pred is true, assuming pred is false up to some point
+// and true after it. It reports false if pred isn't true anywhere in the range.
+func searchFirst(lo uint64, hi uint64, pred func(uint64) (bool, error)) (uint64, bool, error) {
+	var first uint64
+	found := false
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+		ok, err := pred(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			first, found = mid, true
+			if mid == lo {
+				break
+			}
+			hi = mid - 1
+		} else {
+			if mid == hi {
+				break
+			}
+			lo = mid + 1
+		}
+	}
+	return first, found, nil
+}
+
+// ConfirmedNodeAtL1Block returns the node that was the rollup's latest confirmed node as of the given L1 block,
+// i.e. the highest node confirmed at or before that block. Nodes are confirmed or rejected strictly in order, so
+// the first node not yet resolved by that block is binary searched for among nodes 0 through the latest confirmed
+// one; each probe is a log query filtered by the node's topic, starting at its cached creation block. The answer
+// is the closest confirmed node before it, stepping back over any rejected nodes. The number of log queries
+// grows with the logarithm of the number of nodes, but it's still meant for historical analysis rather than hot paths.
+func (r *RollupWatcher) ConfirmedNodeAtL1Block(ctx context.Context, l1Block uint64) (uint64, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	callOpts, cancel := r.getCallOpts(ctx)
+	latestConfirmed, err := r.LatestConfirmed(callOpts)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+	genesisCreated, err := r.getNodeCreationBlock(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+	genesisL1Block, err := r.correspondingL1BlockNumber(ctx, genesisCreated.Uint64())
+	if err != nil {
+		return 0, err
+	}
+	if l1Block < genesisL1Block {
+		return 0, fmt.Errorf("rollup was created at L1 block %v, after requested L1 block %v", genesisL1Block, l1Block)
+	}
+	type resolution struct {
+		resolved  bool
+		confirmed bool
+		l1Block   uint64
+	}
+	resolutions := map[uint64]resolution{
+		// the genesis node is confirmed from the start
+		0: {resolved: true, confirmed: true, l1Block: genesisL1Block},
+	}
+	resolve := func(nodeNum uint64) (resolution, error) {
+		if res, ok := resolutions[nodeNum]; ok {
+			return res, nil
+		}
+		var res resolution
+		confirmation, err := r.LookupNodeConfirmed(ctx, nodeNum)
+		if err != nil {
+			return res, err
+		}
+		if confirmation != nil {
+			res = resolution{resolved: true, confirmed: true, l1Block: confirmation.L1BlockConfirmed}
+		} else {
+			rejected, rejectedAt, err := r.LookupNodeRejected(ctx, nodeNum)
+			if err != nil {
+				return res, err
+			}
+			if rejected {
+				res = resolution{resolved: true, l1Block: rejectedAt.Uint64()}
+			}
+		}
+		resolutions[nodeNum] = res
+		return res, nil
+	}
+	firstUnresolved, found, err := searchFirst(0, latestConfirmed, func(nodeNum uint64) (bool, error) {
+		res, err := resolve(nodeNum)
+		if err != nil {
+			return false, err
+		}
+		return !res.resolved || res.l1Block > l1Block, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		// every node up to the latest confirmed one was resolved by then
+		return latestConfirmed, nil
+	}
+	for nodeNum := firstUnresolved - 1; ; nodeNum-- {
+		res, err := resolve(nodeNum)
+		if err != nil {
+			return 0, err
+		}
+		if res.confirmed {
+			return nodeNum, nil
+		}
+	}
+}
+
+// FirstNodeAfterInboxCount returns the earliest node whose InboxMaxCount is at least inboxCount, i.e. the first
+// node that was able to cover that many inbox messages. The inbox count only grows, so the node is binary searched
+// for, looking up each probed node once. It fails with ErrNoNode if no node reaches the count yet.
+func (r *RollupWatcher) FirstNodeAfterInboxCount(ctx context.Context, inboxCount uint64) (*NodeInfo, error) {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	latestNode, err := r.LatestCreatedNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint64]*NodeInfo)
+	lookupNode := func(nodeNum uint64) (*NodeInfo, error) {
+		if info, ok := nodes[nodeNum]; ok {
+			return info, nil
+		}
+		info, err := r.LookupNode(ctx, nodeNum)
+		if err != nil {
+			return nil, err
+		}
+		nodes[nodeNum] = info
+		return info, nil
+	}
+	nodeNum, found, err := firstNodeReachingInboxCount(latestNode, inboxCount, func(nodeNum uint64) (*big.Int, error) {
+		info, err := lookupNode(nodeNum)
+		if err != nil {
+			return nil, err
+		}
+		return info.InboxMaxCount, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: none of the %v nodes created so far reach inbox count %v", ErrNoNode, latestNode+1, inboxCount)
+	}
+	return lookupNode(nodeNum)
+}
+
+// firstNodeReachingInboxCount searches nodes 0 through latestNode for the first whose inbox max count is at least inboxCount.
+func firstNodeReachingInboxCount(latestNode uint64, inboxCount uint64, inboxMaxCount func(nodeNum uint64) (*big.Int, error)) (uint64, bool, error) {
+	target := new(big.Int).SetUint64(inboxCount)
+	return searchFirst(0, latestNode, func(nodeNum uint64) (bool, error) {
+		count, err := inboxMaxCount(nodeNum)
+		if err != nil {
+			return false, err
+		}
+		return count.Cmp(target) >= 0, nil
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StreamNodeChildren is like LookupNodeChildren, but emits the children one at a time as each log query segment
+// is parsed instead of waiting for the whole scan. Segments are fetched in block order so that each child's hash
+// can be chained from its previous sibling. Both channels are closed when the scan completes, fails or ctx is
+// cancelled; on failure the error is sent on the error channel first. A rangeSize of 0 uses the watcher's default.
+func (r *RollupWatcher) StreamNodeChildren(ctx context.Context, nodeNum uint64, nodeHash common.Hash, rangeSize uint64) (<-chan *NodeInfo, <-chan error) {
+	children := make(chan *NodeInfo)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(children)
+		defer close(errChan)
+		if err := r.streamNodeChildren(ctx, nodeNum, nodeHash, rangeSize, children); err != nil {
+			errChan <- err
+		}
+	}()
+	return children, errChan
+}
+
+func (r *RollupWatcher) streamNodeChildren(ctx context.Context, nodeNum uint64, nodeHash common.Hash, rangeSize uint64, children chan<- *NodeInfo) error {
+	ctx, release, err := r.scopeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	query, fromBlock, toBlock, err := r.childrenQuery(ctx, nodeNum, nodeHash)
+	if err != nil {
+		return err
+	}
+	if query == nil {
+		return nil
+	}
+	if rangeSize == 0 {
+		rangeSize = r.defaultLogQueryRangeSize
+	}
+	var maxRangeSize atomic.Uint64
+	lastHash := nodeHash
+	first := true
+	for _, segment := range splitBlockRange(fromBlock, toBlock, rangeSize) {
+		logs, err := r.filterLogsAdaptive(ctx, *query, segment.from, segment.to, &maxRangeSize)
+		if err != nil {
+			return err
+		}
+		for _, ethLog := range logs {
+			info, err := r.childNodeInfo(ctx, ethLog, lastHash, !first)
+			if err != nil {
+				return err
+			}
+			lastHash = info.NodeHash
+			first = false
+			select {
+			case children <- info:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+// Copyright 2025, Offchain Labs, Inc.
+// For license information, see https://github.com/OffchainLabs/nitro/blob/master/LICENSE.md
+
+package legacystaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/solgen/go/rollup_legacy_gen"
+)
+
+// logsClient serves one log per block and rejects queries spanning more than maxBlocks blocks,
+// with rejection's message if it isn't nil.
+type logsClient struct {
+	RollupWatcherL1Interface
+	mutex     sync.Mutex
+	numBlocks uint64
+	maxBlocks uint64
+	rejection func(from uint64) string
+	queries   []ethereum.FilterQuery
+}
+
+func (c *logsClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.mutex.Lock()
+	c.queries = append(c.queries, q)
+	c.mutex.Unlock()
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	if to-from+1 > c.maxBlocks {
+		if c.rejection != nil {
+			return nil, errors.New(c.rejection(from))
+		}
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	var logs []types.Log
+	for block := from; block <= to && block < c.numBlocks; block++ {
+		logs = append(logs, types.Log{BlockNumber: block})
+	}
+	return logs, nil
+}
+
+func TestFilterLogsAdaptiveShrinksRange(t *testing.T) {
+	client := &logsClient{numBlocks: 100, maxBlocks: 10}
+	r := &RollupWatcher{client: client, retryPolicy: DefaultRetryPolicy}
+	var maxRangeSize atomic.Uint64
+	logs, err := r.filterLogsAdaptive(context.Background(), ethereum.FilterQuery{}, big.NewInt(0), big.NewInt(99), &maxRangeSize)
+	Require(t, err)
+	if len(logs) != 100 {
+		Fail(t, "expected 100 logs, got", len(logs))
+	}
+	for i, l := range logs {
+		if l.BlockNumber != uint64(i) {
+			Fail(t, "log", i, "has block number", l.BlockNumber)
+		}
+	}
+	if size := maxRangeSize.Load(); size == 0 || size > client.maxBlocks {
+		Fail(t, "range size didn't converge below the provider limit, got", size)
+	}
+	// once converged, the remaining queries should all be accepted
+	var rejected int
+	for _, q := range client.queries {
+		if q.ToBlock.Uint64()-q.FromBlock.Uint64()+1 > client.maxBlocks {
+			rejected++
+		}
+	}
+	if rejected != 4 {
+		Fail(t, "expected the range to be halved 4 times, got", rejected, "rejected queries")
+	}
+}
+
+func TestFilterLogsAdaptiveGivesUpAtSingleBlock(t *testing.T) {
+	client := &logsClient{numBlocks: 10, maxBlocks: 0}
+	r := &RollupWatcher{client: client, retryPolicy: DefaultRetryPolicy}
+	var maxRangeSize atomic.Uint64
+	_, err := r.filterLogsAdaptive(context.Background(), ethereum.FilterQuery{}, big.NewInt(0), big.NewInt(9), &maxRangeSize)
+	if err == nil {
+		Fail(t, "expected an error when even a single block is rejected")
+	}
+	if maxRangeSize.Load() != 1 {
+		Fail(t, "expected the range to shrink to a single block, got", maxRangeSize.Load())
+	}
+}
+
+func TestSuggestedLogQueryRangeSize(t *testing.T) {
+	for _, tc := range []struct {
+		message string
+		size    uint64
+	}{
+		{"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range and no limit on the response size, or you can request any block range with a cap of 10K logs in the response. Based on your parameters, this block range should work: [0x10, 0x2f]", 32},
+		{"query returned more than 10000 results. Try with this block range [0x1A2B, 0x1A2C].", 2},
+		{"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range and no limit on the response size", 2000},
+		{"eth_getLogs is limited to a 10,000 range", 10000},
+		{"exceed maximum block range: 5000", 5000},
+		{"block range limit exceeded (3000)", 3000},
+		{"block range is too large, max range is 1000", 1000},
+		{"query returned more than 10000 results", 0},
+		{"response size exceeded", 0},
+	} {
+		if size := suggestedLogQueryRangeSize(errors.New(tc.message)); size != tc.size {
+			Fail(t, "expected suggested range size", tc.size, "got", size, "for", tc.message)
+		}
+	}
+}
+
+func TestFilterLogsAdaptiveJumpsToSuggestedRange(t *testing.T) {
+	client := &logsClient{numBlocks: 100, maxBlocks: 7}
+	client.rejection = func(from uint64) string {
+		return fmt.Sprintf("query returned more than 10000 results. Try with this block range [%#x, %#x].", from, from+client.maxBlocks-1)
+	}
+	r := &RollupWatcher{client: client, retryPolicy: DefaultRetryPolicy}
+	var maxRangeSize atomic.Uint64
+	logs, err := r.filterLogsAdaptive(context.Background(), ethereum.FilterQuery{}, big.NewInt(0), big.NewInt(99), &maxRangeSize)
+	Require(t, err)
+	if len(logs) != 100 {
+		Fail(t, "expected 100 logs, got", len(logs))
+	}
+	if size := maxRangeSize.Load(); size != client.maxBlocks {
+		Fail(t, "expected the range to jump to the suggested size", client.maxBlocks, "got", size)
+	}
+	var rejected int
+	for _, q := range client.queries {
+		if q.ToBlock.Uint64()-q.FromBlock.Uint64()+1 > client.maxBlocks {
+			rejected++
+		}
+	}
+	if rejected != 1 {
+		Fail(t, "expected a single rejected query before converging, got", rejected)
+	}
+}
+
+func TestSplitBlockRangeIsInclusive(t *testing.T) {
+	for _, tc := range []struct {
+		from, to, rangeSize uint64
+	}{
+		{0, 10, 4},
+		{0, 10, 5},
+		{7, 7, 3},
+		{0, 100, 0},
+	} {
+		ranges := splitBlockRange(new(big.Int).SetUint64(tc.from), new(big.Int).SetUint64(tc.to), tc.rangeSize)
+		next := tc.from
+		for _, r := range ranges {
+			if r.from.Uint64() != next {
+				Fail(t, "range starting at", r.from, "expected to start at", next, "for", tc)
+			}
+			if tc.rangeSize != 0 && r.to.Uint64()-r.from.Uint64() > tc.rangeSize {
+				Fail(t, "range", r.from, r.to, "exceeds range size", tc.rangeSize)
+			}
+			next = r.to.Uint64() + 1
+		}
+		if next != tc.to+1 {
+			Fail(t, "ranges end before block", tc.to, "for", tc)
+		}
+	}
+}
+
+// failingClient fails every log query with a transport error.
+type failingClient struct {
+	RollupWatcherL1Interface
+	calls atomic.Int32
+}
+
+func (c *failingClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.calls.Add(1)
+	return nil, errors.New("dial tcp 127.0.0.1:8545: connect: connection refused")
+}
+
+func TestFailoverClientSwitchesToSecondary(t *testing.T) {
+	primary := &failingClient{}
+	secondary := &logsClient{numBlocks: 10, maxBlocks: 10}
+	client := newFailoverClient([]RollupWatcherL1Interface{primary, secondary})
+	query := ethereum.FilterQuery{FromBlock: big.NewInt(0), ToBlock: big.NewInt(9)}
+	for i := 0; i < 3; i++ {
+		logs, err := client.FilterLogs(context.Background(), query)
+		Require(t, err)
+		if len(logs) != 10 {
+			Fail(t, "expected 10 logs, got", len(logs))
+		}
+	}
+	// the secondary stays sticky once the primary has failed
+	if calls := primary.calls.Load(); calls != 1 {
+		Fail(t, "expected the primary to be queried once, got", calls)
+	}
+	if len(secondary.queries) != 3 {
+		Fail(t, "expected the secondary to serve 3 queries, got", len(secondary.queries))
+	}
+}
+
+func TestFailoverClientReturnsErrorWhenAllFail(t *testing.T) {
+	primary := &failingClient{}
+	secondary := &failingClient{}
+	client := newFailoverClient([]RollupWatcherL1Interface{primary, secondary})
+	_, err := client.FilterLogs(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(0), ToBlock: big.NewInt(0)})
+	if err == nil {
+		Fail(t, "expected an error when every client fails")
+	}
+	if primary.calls.Load() != 1 || secondary.calls.Load() != 1 {
+		Fail(t, "expected each client to be tried once, got", primary.calls.Load(), secondary.calls.Load())
+	}
+}
+
+func TestInvalidateOnReorgEvictsReorgedEntries(t *testing.T) {
+	r, err := NewRollupWatcher(common.Address{}, &logsClient{}, bind.CallOpts{}, WithNodeInfoCache(16, time.Minute))
+	Require(t, err)
+	r.creation.Store(&rollup_legacy_gen.RollupUserLogicRollupInitialized{Raw: types.Log{BlockNumber: 50}})
+	// nodes 1 and 2 were created in blocks 100 and 200
+	for nodeNum, block := range map[uint64]uint64{1: 100, 2: 200} {
+		r.nodeCreationCache.Add(nodeNum, new(big.Int).SetUint64(block))
+		r.l1BlockCache.Add(block, block/10)
+		r.nodeInfoCache.Add(nodeNum, nodeInfoCacheEntry{info: &NodeInfo{NodeNum: nodeNum, ParentChainBlockProposed: block}})
+	}
+
+	r.InvalidateOnReorg(150)
+	if !r.nodeCreationCache.Contains(1) || !r.nodeInfoCache.Contains(1) || !r.l1BlockCache.Contains(100) {
+		Fail(t, "entries from before the reorg were evicted")
+	}
+	if r.nodeCreationCache.Contains(2) || r.nodeInfoCache.Contains(2) || r.l1BlockCache.Contains(200) {
+		Fail(t, "entries from reorged blocks were kept")
+	}
+	if r.creation.Load() == nil {
+		Fail(t, "creation event from before the reorg was evicted")
+	}
+
+	r.InvalidateOnReorg(50)
+	if r.nodeCreationCache.Len() != 0 || r.nodeInfoCache.Len() != 0 || r.l1BlockCache.Len() != 0 {
+		Fail(t, "entries from reorged blocks were kept")
+	}
+	if r.creation.Load() != nil {
+		Fail(t, "creation event from a reorged block was kept")
+	}
+}
+
+func challengeStarted(index uint64, node uint64) *rollup_legacy_gen.RollupUserLogicRollupChallengeStarted {
+	return &rollup_legacy_gen.RollupUserLogicRollupChallengeStarted{
+		ChallengeIndex: index,
+		Asserter:       common.BigToAddress(new(big.Int).SetUint64(2*index + 1)),
+		Challenger:     common.BigToAddress(new(big.Int).SetUint64(2*index + 2)),
+		ChallengedNode: node,
+	}
+}
+
+func TestActiveChallengesNone(t *testing.T) {
+	challenges, err := activeChallenges(nil, func(uint64) (bool, error) {
+		Fail(t, "no challenges to check")
+		return false, nil
+	})
+	Require(t, err)
+	if len(challenges) != 0 {
+		Fail(t, "expected no challenges, got", challenges)
+	}
+}
+
+func TestActiveChallengesOne(t *testing.T) {
+	ev := challengeStarted(1, 10)
+	challenges, err := activeChallenges([]*rollup_legacy_gen.RollupUserLogicRollupChallengeStarted{ev}, func(uint64) (bool, error) {
+		return true, nil
+	})
+	Require(t, err)
+	if len(challenges) != 1 {
+		Fail(t, "expected one challenge, got", challenges)
+	}
+	expected := ChallengeInfo{Index: 1, Asserter: ev.Asserter, Challenger: ev.Challenger, ChallengedNode: 10}
+	if challenges[0] != expected {
+		Fail(t, "got challenge", challenges[0], "expected", expected)
+	}
+}
+
+func TestActiveChallengesMany(t *testing.T) {
+	events := []*rollup_legacy_gen.RollupUserLogicRollupChallengeStarted{
+		challengeStarted(1, 10),
+		challengeStarted(2, 10),
+		challengeStarted(3, 11),
+		challengeStarted(2, 10),
+		challengeStarted(4, 12),
+	}
+	resolved := map[uint64]bool{3: true}
+	checked := make(map[uint64]int)
+	challenges, err := activeChallenges(events, func(index uint64) (bool, error) {
+		checked[index]++
+		return !resolved[index], nil
+	})
+	Require(t, err)
+	var indexes []uint64
+	for _, challenge := range challenges {
+		indexes = append(indexes, challenge.Index)
+	}
+	if len(indexes) != 3 || indexes[0] != 1 || indexes[1] != 2 || indexes[2] != 4 {
+		Fail(t, "expected active challenges 1, 2 and 4 in order, got", indexes)
+	}
+	for index, count := range checked {
+		if count != 1 {
+			Fail(t, "challenge", index, "checked", count, "times")
+		}
+	}
+}
+
+func TestFirstNodeReachingInboxCount(t *testing.T) {
+	inboxMaxCounts := []int64{1, 5, 5, 9, 12}
+	inboxMaxCount := func(nodeNum uint64) (*big.Int, error) {
+		return big.NewInt(inboxMaxCounts[nodeNum]), nil
+	}
+	latestNode := uint64(len(inboxMaxCounts) - 1)
+	for _, tc := range []struct {
+		inboxCount uint64
+		node       uint64
+		found      bool
+	}{
+		{0, 0, true},
+		{1, 0, true},
+		{2, 1, true},
+		{4, 1, true},
+		{5, 1, true},
+		{6, 3, true},
+		{9, 3, true},
+		{10, 4, true},
+		{12, 4, true},
+		{13, 0, false},
+	} {
+		node, found, err := firstNodeReachingInboxCount(latestNode, tc.inboxCount, inboxMaxCount)
+		Require(t, err)
+		if found != tc.found || (found && node != tc.node) {
+			Fail(t, "inbox count", tc.inboxCount, "expected node", tc.node, "found", tc.found, "got node", node, "found", found)
+		}
+	}
+	node, found, err := firstNodeReachingInboxCount(0, 1, inboxMaxCount)
+	Require(t, err)
+	if !found || node != 0 {
+		Fail(t, "expected the only node to reach inbox count 1, got", node, found)
+	}
+	_, found, err = firstNodeReachingInboxCount(0, 2, inboxMaxCount)
+	Require(t, err)
+	if found {
+		Fail(t, "expected no node to reach inbox count 2 when only the genesis node exists")
+	}
+}
+
+func TestSegmentBoundaryChecks(t *testing.T) {
+	client := &logsClient{numBlocks: 30, maxBlocks: 30}
+	r := &RollupWatcher{client: client, retryPolicy: DefaultRetryPolicy, checkSegmentBoundaries: true}
+	logs, err := r.filterLogsInRange(context.Background(), ethereum.FilterQuery{}, big.NewInt(0), big.NewInt(29), 9, 0, nil)
+	Require(t, err)
+	if len(logs) != 30 {
+		Fail(t, "expected 30 logs, got", len(logs))
+	}
+	segments := splitBlockRange(big.NewInt(0), big.NewInt(29), 9)
+	missed, err := r.countMissedBoundaryLogs(context.Background(), ethereum.FilterQuery{}, segments, logs)
+	Require(t, err)
+	if missed != 0 {
+		Fail(t, "expected no missed logs, got", missed)
+	}
+	// drop the log of the first block of the second segment, as an off-by-one in range splitting would
+	var withGap []types.Log
+	for _, l := range logs {
+		if l.BlockNumber != segments[1].from.Uint64() {
+			withGap = append(withGap, l)
+		}
+	}
+	missed, err = r.countMissedBoundaryLogs(context.Background(), ethereum.FilterQuery{}, segments, withGap)
+	Require(t, err)
+	if missed != 1 {
+		Fail(t, "expected the dropped boundary log to be detected, got", missed, "missed logs")
+	}
+}
+
+func TestChildNodeHashChaining(t *testing.T) {
+	// a "hash" that keeps the sibling flag and the first byte of each input, so the chaining is visible
+	r := &RollupWatcher{nodeHashFunc: func(data ...[]byte) common.Hash {
+		var hash common.Hash
+		for i, d := range data {
+			hash[i] = d[0]
+		}
+		return hash
+	}}
+	parent := common.Hash{0xaa}
+	first := r.childNodeHash(parent, false, common.Hash{1}, common.Hash{2}, common.Hash{3})
+	if first != (common.Hash{0, 0xaa, 1, 2, 3}) {
+		Fail(t, "unexpected first child hash", first)
+	}
+	second := r.childNodeHash(first, true, common.Hash{4}, common.Hash{5}, common.Hash{6})
+	if second != (common.Hash{1, 0, 4, 5, 6}) {
+		Fail(t, "unexpected second child hash", second)
+	}
+
+	// without a hash function, the rollup contract's keccak256 is used
+	r = &RollupWatcher{}
+	executionHash, inboxAcc, wasmModuleRoot := common.Hash{1}, common.Hash{2}, common.Hash{3}
+	expected := crypto.Keccak256Hash([]byte{1}, parent[:], executionHash[:], inboxAcc[:], wasmModuleRoot[:])
+	if hash := r.childNodeHash(parent, true, executionHash, inboxAcc, wasmModuleRoot); hash != expected {
+		Fail(t, "expected the keccak256 hash", expected, "got", hash)
+	}
+}
+
+func TestMultiRollupWatcherSharesFailoverClient(t *testing.T) {
+	addresses := []common.Address{{1}, {2}}
+	m, err := NewMultiRollupWatcher(addresses, &logsClient{}, bind.CallOpts{}, WithFallbackClients(&logsClient{}))
+	Require(t, err)
+	if _, ok := m.Client().(*failoverClient); !ok {
+		Fail(t, "expected the shared client to fail over")
+	}
+	for _, address := range addresses {
+		watcher, _ := m.Watcher(address)
+		if watcher.Client() != m.Client() {
+			Fail(t, "watcher for", address, "doesn't use the shared failover client")
+		}
+	}
+}
@@ -6,30 +6,180 @@ package stopwaiter
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"reflect"
 	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/sync/semaphore"
+
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 
 	"github.com/offchainlabs/nitro/util/containers"
 )
 
 const stopDelayWarningTimeout = 30 * time.Second
 
+const droppedLaunchLogInterval = 10 * time.Second
+
+// ErrStopped is the cause of a StopWaiter's context being cancelled by StopOnly.
+var ErrStopped = errors.New("stopwaiter stopped")
+
+// ErrStopTimedOut is matched by *StopTimedOutError.
+var ErrStopTimedOut = errors.New("timed out waiting for threads to stop")
+
+// StopTimedOutError reports threads still running after StopAndWaitWithTimeout or GetWaitChannelWithTimeout timed out.
+type StopTimedOutError struct {
+	Name    string
+	Timeout time.Duration
+	// NamedThreads are the names of the still running threads that were launched with a name.
+	NamedThreads []string
+}
+
+func (e *StopTimedOutError) Error() string {
+	return fmt.Sprintf("%v timed out after %v waiting for threads to stop, named threads still running: %v", e.Name, e.Timeout, e.NamedThreads)
+}
+
+func (e *StopTimedOutError) Is(target error) bool {
+	return target == ErrStopTimedOut
+}
+
+var (
+	threadPanicCounter   = metrics.NewRegisteredCounter("arb/stopwaiter/thread/panic", nil)
+	threadRestartCounter = metrics.NewRegisteredCounter("arb/stopwaiter/thread/restart", nil)
+	threadLifetimeHist   = metrics.NewRegisteredHistogram("arb/stopwaiter/thread/lifetime", nil, metrics.NewBoundedHistogramSample())
+)
+
+// componentMetrics are the metrics of a running StopWaiter, labelled with the name of its type. Instances of the
+// same type running at once get an index appended to the label, e.g. "arb/stopwaiter/component/Foo_1". The metrics
+// are unregistered and the index released once the StopWaiter is waited on and drains, e.g. in StopAndWait,
+// so short-lived instances reuse labels rather than growing the registry.
+type componentMetrics struct {
+	label          string
+	index          int
+	activeThreads  *metrics.Gauge
+	threadLifetime metrics.Histogram
+	stopDuration   metrics.Histogram
+}
+
+var (
+	componentMetricsMutex   sync.Mutex
+	componentMetricsIndices = make(map[string]map[int]struct{}) // in use indices, by sanitized type name
+)
+
+// acquireComponentMetrics registers the metrics for a StopWaiter of the component called name,
+// under the lowest index not in use by another running instance of that component.
+func acquireComponentMetrics(name string) *componentMetrics {
+	label := sanitizeMetricLabel(name)
+	componentMetricsMutex.Lock()
+	defer componentMetricsMutex.Unlock()
+	indices := componentMetricsIndices[label]
+	if indices == nil {
+		indices = make(map[int]struct{})
+		componentMetricsIndices[label] = indices
+	}
+	index := 0
+	for {
+		if _, inUse := indices[index]; !inUse {
+			break
+		}
+		index++
+	}
+	indices[index] = struct{}{}
+	m := &componentMetrics{label: label, index: index}
+	prefix := m.prefix()
+	m.activeThreads = metrics.GetOrRegisterGauge(prefix+"/threads/active", nil)
+	m.threadLifetime = metrics.GetOrRegisterHistogram(prefix+"/thread/lifetime", nil, metrics.NewBoundedHistogramSample())
+	m.stopDuration = metrics.GetOrRegisterHistogram(prefix+"/stop/duration", nil, metrics.NewBoundedHistogramSample())
+	return m
+}
+
+func (m *componentMetrics) prefix() string {
+	if m.index == 0 {
+		return "arb/stopwaiter/component/" + m.label
+	}
+	return fmt.Sprintf("arb/stopwaiter/component/%v_%v", m.label, m.index)
+}
+
+// release unregisters the metrics and frees their index for the next instance of the component.
+func (m *componentMetrics) release() {
+	prefix := m.prefix()
+	componentMetricsMutex.Lock()
+	defer componentMetricsMutex.Unlock()
+	metrics.Unregister(prefix + "/threads/active")
+	metrics.Unregister(prefix + "/thread/lifetime")
+	metrics.Unregister(prefix + "/stop/duration")
+	indices := componentMetricsIndices[m.label]
+	delete(indices, m.index)
+	if len(indices) == 0 {
+		delete(componentMetricsIndices, m.label)
+	}
+}
+
+// sanitizeMetricLabel replaces the characters of a type name, like dots and brackets, that aren't allowed in metric names.
+func sanitizeMetricLabel(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+const (
+	replayableThreadBaseBackoff = time.Second
+	replayableThreadMaxBackoff  = time.Minute
+)
+
 type StopWaiterSafe struct {
-	mutex     sync.Mutex // protects started, stopped, ctx, parentCtx, stopFunc
-	started   bool
-	stopped   bool
-	ctx       context.Context
-	parentCtx context.Context
-	stopFunc  func()
-	name      string
-	waitChan  <-chan interface{}
+	mutex              sync.Mutex // protects the fields up to wg
+	started            bool
+	stopped            bool
+	ctx                context.Context
+	parentCtx          context.Context
+	stopFunc           context.CancelCauseFunc
+	stopCause          error
+	startedAt          time.Time
+	name               string
+	waitChan           <-chan interface{}
+	stopWarningTimeout time.Duration
+	repeatStopWarnings bool
+	escalationFactor   int
+	escalationHandler  func(name string)
+	scopedStackTraces  bool
+	logger             log.Logger
+	recoverPanics      bool
+	maxThreadRestarts  int
+	threadSlots        *semaphore.Weighted
+	supervisedRestarts map[string]*atomic.Int64
+	panicHandler       PanicHandler
+	onStopHooks        []func()
+	onStopHooksRan     bool
+	finalizers         []func()
+	allowRestart       bool
+	parentWaiter       *StopWaiterSafe
+	children           []childStopWaiter
+	metrics            *componentMetrics
 
-	wg sync.WaitGroup
+	wg            sync.WaitGroup
+	activeThreads atomic.Int64
+
+	startOnceMutex sync.Mutex // serializes StartOnce calls
+
+	droppedLaunches      atomic.Int64 // since the last log about them
+	lastDroppedLaunchLog atomic.Int64 // in unix nanoseconds
+
+	namedThreadsMutex sync.Mutex // protects namedThreads, nextThreadId
+	namedThreads      map[uint64]ThreadStatus
+	nextThreadId      uint64
 }
 
 func (s *StopWaiterSafe) Started() bool {
@@ -44,6 +194,31 @@ func (s *StopWaiterSafe) Stopped() bool {
 	return s.stopped
 }
 
+// StartedAt returns when Start was last called.
+func (s *StopWaiterSafe) StartedAt() (time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.started {
+		return time.Time{}, errors.New("not started")
+	}
+	return s.startedAt, nil
+}
+
+// Uptime returns how long ago Start was last called, even if this has since been stopped.
+func (s *StopWaiterSafe) Uptime() (time.Duration, error) {
+	startedAt, err := s.StartedAt()
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(startedAt), nil
+}
+
+func (s *StopWaiterSafe) getComponentMetrics() *componentMetrics {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.metrics
+}
+
 func (s *StopWaiterSafe) GetContextSafe() (context.Context, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -83,38 +258,389 @@ func (s *StopWaiterSafe) Start(ctx context.Context, parent any) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	if s.started {
-		return errors.New("start after start")
+		if !s.allowRestart || !s.drained() {
+			return errors.New("start after start")
+		}
+		s.stopped = false
+		s.stopCause = nil
+		s.waitChan = nil
+		s.onStopHooksRan = false
+	}
+	var parentWaiterCtx context.Context
+	if s.parentWaiter != nil {
+		var err error
+		parentWaiterCtx, err = s.parentWaiter.GetContextSafe()
+		if err != nil {
+			return fmt.Errorf("child started before its parent: %w", err)
+		}
 	}
 	s.started = true
+	s.startedAt = time.Now()
 	s.name = getParentName(parent)
+	// released once drained, see GetWaitChannel
+	s.metrics = acquireComponentMetrics(s.name)
 	s.parentCtx = ctx
-	s.ctx, s.stopFunc = context.WithCancel(s.parentCtx)
+	s.ctx, s.stopFunc = context.WithCancelCause(s.parentCtx)
+	if parentWaiterCtx != nil {
+		cancel := s.stopFunc
+		stopAfterParent := context.AfterFunc(parentWaiterCtx, func() {
+			cancel(context.Cause(parentWaiterCtx))
+		})
+		s.stopFunc = func(cause error) {
+			stopAfterParent()
+			cancel(cause)
+		}
+	}
 	if s.stopped {
-		s.stopFunc()
+		s.stopFunc(s.stopCause)
+	}
+	return nil
+}
+
+// StartOnce is Start, except that it only starts this if it isn't already running, and any call made concurrently
+// with it blocks until it's done rather than erroring. Every call returns this's context.
+// If restarting is allowed, StartOnce starts this again once it has been stopped and drained, like Start would;
+// otherwise a stopped StopWaiter's cancelled context is returned. If starting fails, the error isn't kept,
+// so a later call tries again.
+func (s *StopWaiterSafe) StartOnce(ctx context.Context, parent any) (context.Context, error) {
+	s.startOnceMutex.Lock()
+	defer s.startOnceMutex.Unlock()
+	s.mutex.Lock()
+	running := s.started && !(s.allowRestart && s.drained())
+	s.mutex.Unlock()
+	if !running {
+		if err := s.Start(ctx, parent); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetContextSafe()
+}
+
+// DefaultChildStopPriority is the priority of children added with AddChild.
+const DefaultChildStopPriority = 0
+
+type childStopWaiter struct {
+	waiter   *StopWaiterSafe
+	priority int
+}
+
+// AddChild is AddChildWithPriority with DefaultChildStopPriority.
+func (s *StopWaiterSafe) AddChild(child *StopWaiterSafe) error {
+	return s.AddChildWithPriority(child, DefaultChildStopPriority)
+}
+
+// AddChildWithPriority makes child stop when this does, before this cancels its own context.
+// Children are stopped with StopAndWait in tiers of decreasing priority, each tier being stopped concurrently
+// and fully drained before the next one is stopped, so a child's threads see their context cancelled only
+// once the higher priority tiers have returned, and this's threads only once every child has.
+// The child must not have been started yet, and will fail to start before this does. If this's context is
+// cancelled some other way, such as through the context this was started with, the child's is too.
+func (s *StopWaiterSafe) AddChildWithPriority(child *StopWaiterSafe, priority int) error {
+	if child == s {
+		return errors.New("stop waiter can't be its own child")
+	}
+	child.mutex.Lock()
+	if child.started {
+		child.mutex.Unlock()
+		return errors.New("child already started")
 	}
+	if child.parentWaiter != nil {
+		child.mutex.Unlock()
+		return errors.New("child already has a parent")
+	}
+	child.parentWaiter = s
+	child.mutex.Unlock()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.children = append(s.children, childStopWaiter{waiter: child, priority: priority})
 	return nil
 }
 
+// stopChildren stops the children added with AddChildWithPriority, highest priority tier first.
+func (s *StopWaiterSafe) stopChildren() {
+	s.mutex.Lock()
+	children := append([]childStopWaiter(nil), s.children...)
+	s.mutex.Unlock()
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].priority > children[j].priority
+	})
+	for start := 0; start < len(children); {
+		end := start + 1
+		for end < len(children) && children[end].priority == children[start].priority {
+			end++
+		}
+		var tier sync.WaitGroup
+		for _, child := range children[start:end] {
+			tier.Add(1)
+			go func() {
+				defer tier.Done()
+				if err := child.waiter.StopAndWait(); err != nil {
+					s.getLogger().Error("error stopping child", "name", s.name, "child", child.waiter.name, "err", err)
+				}
+			}()
+		}
+		tier.Wait()
+		start = end
+	}
+}
+
+// SetAllowRestart lets Start be called again once StopAndWait has returned, starting over with a fresh context.
+// Threads must not be launched concurrently with the restart, as they could still see the old, cancelled context.
+func (s *StopWaiterSafe) SetAllowRestart(allow bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.allowRestart = allow
+}
+
+// drained returns whether this was stopped and all its threads have returned.
+// Only call this internally with the mutex held.
+func (s *StopWaiterSafe) drained() bool {
+	if !s.stopped || s.waitChan == nil {
+		return false
+	}
+	select {
+	case <-s.waitChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// RegisterOnStop registers a hook run when stopping begins, before threads see their context cancelled.
+// Hooks run once, in the reverse order they were registered. A hook registered once stopping has begun runs immediately.
+func (s *StopWaiterSafe) RegisterOnStop(hook func()) {
+	s.mutex.Lock()
+	if !s.onStopHooksRan {
+		s.onStopHooks = append(s.onStopHooks, hook)
+		s.mutex.Unlock()
+		return
+	}
+	s.mutex.Unlock()
+	hook()
+}
+
+// RegisterFinalizer registers a function run once all threads have returned, before waiting for them completes.
+// Finalizers run once, in the order they were registered.
+func (s *StopWaiterSafe) RegisterFinalizer(finalizer func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.finalizers = append(s.finalizers, finalizer)
+}
+
+func (s *StopWaiterSafe) runFinalizers() {
+	s.mutex.Lock()
+	finalizers := s.finalizers
+	s.finalizers = nil
+	s.mutex.Unlock()
+	for _, finalizer := range finalizers {
+		finalizer()
+	}
+}
+
+// runOnStopHooks runs the hooks registered with RegisterOnStop, unless they've already run.
+// The mutex isn't held while they run, so they may call back into the StopWaiter.
+func (s *StopWaiterSafe) runOnStopHooks() {
+	s.mutex.Lock()
+	hooks := s.onStopHooks
+	s.onStopHooks = nil
+	s.onStopHooksRan = true
+	s.mutex.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
 func (s *StopWaiterSafe) StopOnly() {
+	s.StopOnlyWithCause(ErrStopped)
+}
+
+// StopOnlyWithCause is StopOnly, with cause as the cause of the context's cancellation, for threads to
+// read with context.Cause. Only the first call to stop sets the cause. A nil cause is replaced with ErrStopped.
+func (s *StopWaiterSafe) StopOnlyWithCause(cause error) {
+	if cause == nil {
+		cause = ErrStopped
+	}
+	s.runOnStopHooks()
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if s.started && !s.stopped {
-		s.stopFunc()
+	if !s.stopped {
+		s.stopCause = cause
+		if s.started {
+			if len(s.children) == 0 {
+				s.stopFunc(cause)
+			} else {
+				// this keeps running until its children have drained, highest priority tier first
+				stopFunc := s.stopFunc
+				go func() {
+					s.stopChildren()
+					stopFunc(cause)
+				}()
+			}
+		}
 	}
 	s.stopped = true
 }
 
+// GetStopCause returns the cause this was stopped with, see StopOnlyWithCause, or nil if it hasn't been stopped.
+func (s *StopWaiterSafe) GetStopCause() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stopCause
+}
+
+// SetStopWarningTimeout sets how long StopAndWait waits before warning that stopping is taking too long.
+// Defaults to stopDelayWarningTimeout.
+func (s *StopWaiterSafe) SetStopWarningTimeout(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("stop warning timeout must be positive, got %v", d)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopWarningTimeout = d
+	return nil
+}
+
+func (s *StopWaiterSafe) getStopWarningTimeout() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopWarningTimeout == 0 {
+		return stopDelayWarningTimeout
+	}
+	return s.stopWarningTimeout
+}
+
+// SetRepeatStopWarnings makes StopAndWait keep warning every stop warning timeout until stopping completes,
+// rather than only once. Repeated warnings are compact, listing the still running named threads and how long
+// they've been running for, without stack traces.
+func (s *StopWaiterSafe) SetRepeatStopWarnings(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.repeatStopWarnings = enabled
+}
+
+func (s *StopWaiterSafe) getRepeatStopWarnings() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.repeatStopWarnings
+}
+
 // StopAndWait may be called multiple times, even before start.
 func (s *StopWaiterSafe) StopAndWait() error {
-	return s.stopAndWaitImpl(stopDelayWarningTimeout)
+	return s.stopAndWaitImpl(s.getStopWarningTimeout())
+}
+
+// StopAndWaitAll stops all of ws at once, then waits for all of them concurrently,
+// so they drain in parallel rather than one after another.
+func StopAndWaitAll(ws ...*StopWaiterSafe) error {
+	for _, w := range ws {
+		w.StopOnly()
+	}
+	errs := make([]error, len(ws))
+	var wg sync.WaitGroup
+	for i, w := range ws {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = w.StopAndWait()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close is equivalent to StopAndWait, so that a StopWaiter can be used as an io.Closer.
+func (s *StopWaiterSafe) Close() error {
+	return s.stopAndWaitImpl(s.getStopWarningTimeout())
+}
+
+// SetLogger sets the logger used for this StopWaiter's messages, instead of the root logger.
+func (s *StopWaiterSafe) SetLogger(logger log.Logger) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.logger = logger
+}
+
+func (s *StopWaiterSafe) getLogger() log.Logger {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.logger == nil {
+		return log.Root()
+	}
+	return s.logger
+}
+
+// launcherLogger returns the logger installed with SetLogger if s is a StopWaiter, or the root logger otherwise.
+func launcherLogger(s ThreadLauncher) log.Logger {
+	if withLogger, ok := s.(interface{ getLogger() log.Logger }); ok {
+		return withLogger.getLogger()
+	}
+	return log.Root()
+}
+
+// stopWaiterLabel is the pprof label identifying the StopWaiter that launched a named thread.
+const stopWaiterLabel = "stopwaiter"
+
+func (s *StopWaiterSafe) labelValue() string {
+	return fmt.Sprintf("%p", s)
+}
+
+// SetScopedStackTraces makes the stack traces logged when stopping takes too long only include the
+// goroutines of threads launched with a name, and any they started, rather than every goroutine.
+// All of them are still logged if there aren't any such goroutines.
+func (s *StopWaiterSafe) SetScopedStackTraces(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.scopedStackTraces = enabled
+}
+
+func (s *StopWaiterSafe) getStackTraces() string {
+	s.mutex.Lock()
+	scoped := s.scopedStackTraces
+	s.mutex.Unlock()
+	if scoped {
+		if traces := s.getLabelledStackTraces(); traces != "" {
+			return traces
+		}
+	}
+	return getAllStackTraces()
 }
 
+// getLabelledStackTraces returns the stack traces of goroutines labelled as this StopWaiter's.
+func (s *StopWaiterSafe) getLabelledStackTraces() string {
+	var buf strings.Builder
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		s.getLogger().Warn("failed to get goroutine profile", "name", s.name, "err", err)
+		return ""
+	}
+	label := fmt.Sprintf("%q:%q", stopWaiterLabel, s.labelValue())
+	var traces []string
+	// each group of identical goroutines is a paragraph listing their labels and stack
+	for _, group := range strings.Split(buf.String(), "\n\n") {
+		if strings.Contains(group, label) {
+			traces = append(traces, group)
+		}
+	}
+	return strings.Join(traces, "\n\n")
+}
+
+// The stack traces logged when stopping takes too long are first read into a buffer of
+// InitialStackTraceBufferSize bytes, doubled as long as that's too small, up to MaxStackTraceBufferSize.
+var (
+	InitialStackTraceBufferSize = 64 * 1024
+	MaxStackTraceBufferSize     = 64 * 1024 * 1024
+)
+
 func getAllStackTraces() string {
-	buf := make([]byte, 64*1024*1024)
-	size := runtime.Stack(buf, true)
-	builder := strings.Builder{}
-	builder.Write(buf[0:size])
-	return builder.String()
+	bufSize := max(min(InitialStackTraceBufferSize, MaxStackTraceBufferSize), 1)
+	for {
+		buf := make([]byte, bufSize)
+		size := runtime.Stack(buf, true)
+		if size < bufSize || bufSize >= MaxStackTraceBufferSize {
+			return string(buf[:size])
+		}
+		bufSize = min(bufSize*2, MaxStackTraceBufferSize)
+	}
 }
 
 func (s *StopWaiterSafe) stopAndWaitImpl(warningTimeout time.Duration) error {
@@ -131,21 +657,90 @@ func (s *StopWaiterSafe) stopAndWaitImpl(warningTimeout time.Duration) error {
 	if err != nil {
 		return err
 	}
+	stopStart := time.Now()
 	timer := time.NewTimer(warningTimeout)
 
 	select {
 	case <-timer.C:
-		traces := getAllStackTraces()
-		log.Warn("taking too long to stop", "name", s.name, "delay[s]", warningTimeout.Seconds())
-		log.Warn(traces)
+		traces := s.getStackTraces()
+		s.getLogger().Warn("taking too long to stop", "name", s.name, "delay[s]", warningTimeout.Seconds(), "namedThreads", s.runningNamedThreads())
+		s.getLogger().Warn(traces)
 	case <-waitChan:
 		timer.Stop()
 		return nil
 	}
-	<-waitChan
+	factor, handler := s.getStopEscalation()
+	var escalationChan <-chan time.Time
+	if factor > 0 {
+		escalationTimer := time.NewTimer(warningTimeout * time.Duration(factor-1))
+		defer escalationTimer.Stop()
+		escalationChan = escalationTimer.C
+	}
+	var repeatChan <-chan time.Time
+	if s.getRepeatStopWarnings() {
+		repeatTicker := time.NewTicker(warningTimeout)
+		defer repeatTicker.Stop()
+		repeatChan = repeatTicker.C
+	}
+	for {
+		select {
+		case <-escalationChan:
+			escalationChan = nil
+			s.getLogger().Error("stop is stuck, escalating", "name", s.name, "delay[s]", (warningTimeout * time.Duration(factor)).Seconds(), "namedThreads", s.runningNamedThreads())
+			s.getLogger().Error(s.getStackTraces())
+			if handler == nil {
+				panic(fmt.Sprintf("%v failed to stop within %v", s.name, warningTimeout*time.Duration(factor)))
+			}
+			handler(s.name)
+		case <-repeatChan:
+			s.getLogger().Warn("still taking too long to stop", "name", s.name, "delay[s]", time.Since(stopStart).Seconds(), "activeThreads", s.ActiveThreadCount(), "namedThreads", s.namedThreadRunningTimes())
+		case <-waitChan:
+			return nil
+		}
+	}
+}
+
+// SetStopEscalation makes StopAndWait escalate once stopping has taken factor times the stop warning timeout,
+// logging all stack traces again and then calling handler, or panicking if handler is nil, rather than waiting
+// forever. If handler returns, StopAndWait carries on waiting. A factor of zero, the default, disables this.
+func (s *StopWaiterSafe) SetStopEscalation(factor int, handler func(name string)) error {
+	if factor < 0 {
+		return fmt.Errorf("stop escalation factor must not be negative, got %v", factor)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.escalationFactor = factor
+	s.escalationHandler = handler
 	return nil
 }
 
+func (s *StopWaiterSafe) getStopEscalation() (int, func(name string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.escalationFactor, s.escalationHandler
+}
+
+// StopAndWaitWithTimeout is StopAndWait, except that it gives up waiting after timeout,
+// returning a *StopTimedOutError so the caller can decide whether to carry on regardless.
+func (s *StopWaiterSafe) StopAndWaitWithTimeout(timeout time.Duration) error {
+	s.StopOnly()
+	if !s.Started() {
+		return nil
+	}
+	waitChan, err := s.GetWaitChannel()
+	if err != nil {
+		return err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-waitChan:
+		return nil
+	case <-timer.C:
+		return &StopTimedOutError{Name: s.name, Timeout: timeout, NamedThreads: s.runningNamedThreads()}
+	}
+}
+
 func (s *StopWaiterSafe) GetWaitChannel() (<-chan interface{}, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -154,10 +749,17 @@ func (s *StopWaiterSafe) GetWaitChannel() (<-chan interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		component := s.metrics
 		waitChan := make(chan interface{})
 		go func() {
 			<-ctx.Done()
+			stopStart := time.Now()
+			// StopOnly already stopped the children before cancelling ctx, unless it was cancelled some other way
+			s.stopChildren()
 			s.wg.Wait()
+			s.runFinalizers()
+			component.stopDuration.Update(time.Since(stopStart).Nanoseconds())
+			component.release()
 			close(waitChan)
 		}()
 		s.waitChan = waitChan
@@ -165,77 +767,733 @@ func (s *StopWaiterSafe) GetWaitChannel() (<-chan interface{}, error) {
 	return s.waitChan, nil
 }
 
+// GetWaitChannelWithTimeout is GetWaitChannel, except that the channel also signals after timeout.
+// The channel receives nil once everything has drained, or a *StopTimedOutError if timeout passes first,
+// and is then closed.
+func (s *StopWaiterSafe) GetWaitChannelWithTimeout(timeout time.Duration) (<-chan interface{}, error) {
+	waitChan, err := s.GetWaitChannel()
+	if err != nil {
+		return nil, err
+	}
+	result := make(chan interface{}, 1)
+	go func() {
+		defer close(result)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-waitChan:
+			result <- nil
+		case <-timer.C:
+			result <- &StopTimedOutError{Name: s.name, Timeout: timeout, NamedThreads: s.runningNamedThreads()}
+		}
+	}()
+	return result, nil
+}
+
 // If stop was already called, thread might silently not be launched
 func (s *StopWaiterSafe) LaunchThreadSafe(foo func(context.Context)) error {
+	return ignoreStopped(s.launchThread("", foo))
+}
+
+// LaunchThreadStrictSafe is LaunchThreadSafe, except that it returns ErrStopped rather than
+// silently not launching the thread if stop was already called.
+func (s *StopWaiterSafe) LaunchThreadStrictSafe(foo func(context.Context)) error {
+	return s.launchThread("", foo)
+}
+
+// LaunchThreadWithNameSafe is LaunchThreadSafe for a thread that's labelled with name in profiles,
+// and listed by name if it's still running when stopping takes too long. Its lifetime is recorded in a histogram
+// of its own, so name must be low-cardinality, see threadMetricName.
+func (s *StopWaiterSafe) LaunchThreadWithNameSafe(name string, foo func(context.Context)) error {
+	return ignoreStopped(s.launchThread(name, foo))
+}
+
+func ignoreStopped(err error) error {
+	if errors.Is(err, ErrStopped) {
+		return nil
+	}
+	return err
+}
+
+// launchThread returns ErrStopped if stop was already called.
+func (s *StopWaiterSafe) launchThread(name string, foo func(context.Context)) error {
 	ctx, err := s.GetContextSafe()
 	if err != nil {
 		return err
 	}
 	if s.Stopped() {
-		return nil
+		s.logDroppedLaunch(name)
+		return ErrStopped
 	}
+	recoverPanics := s.getRecoverPanics()
+	component := s.getComponentMetrics()
 	s.wg.Add(1)
+	s.activeThreads.Add(1)
+	component.activeThreads.Inc(1)
 	go func() {
-		foo(ctx)
-		s.wg.Done()
+		defer s.wg.Done()
+		defer s.activeThreads.Add(-1)
+		defer component.activeThreads.Dec(1)
+		defer recordThreadLifetime(component, name, time.Now())
+		if recoverPanics {
+			defer s.recoverThreadPanic()
+		}
+		if name == "" {
+			foo(ctx)
+			return
+		}
+		defer s.unregisterNamedThread(s.registerNamedThread(name))
+		pprof.Do(ctx, pprof.Labels("thread", name, stopWaiterLabel, s.labelValue()), foo)
 	}()
 	return nil
 }
 
-// This calls go foo() directly, with the benefit of being easily searchable.
-// Callers may rely on the assumption that foo runs even if this is stopped.
-func (s *StopWaiterSafe) LaunchUntrackedThread(foo func()) {
-	go foo()
+// recordThreadLifetime records how long a thread ran, for its component and in a histogram of its own if it's named.
+func recordThreadLifetime(component *componentMetrics, name string, start time.Time) {
+	lifetime := time.Since(start).Nanoseconds()
+	threadLifetimeHist.Update(lifetime)
+	component.threadLifetime.Update(lifetime)
+	if name != "" {
+		metrics.GetOrRegisterHistogram(threadMetricName("arb/stopwaiter/thread/lifetime", name, ""), nil, metrics.NewBoundedHistogramSample()).Update(lifetime)
+	}
 }
 
-// CallIteratively calls function iteratively in a thread.
-// input param return value is how long to wait before next invocation
-func (s *StopWaiterSafe) CallIterativelySafe(foo func(context.Context) time.Duration) error {
-	return s.LaunchThreadSafe(func(ctx context.Context) {
-		for {
-			interval := foo(ctx)
-			if ctx.Err() != nil {
-				return
-			}
-			if interval == time.Duration(0) {
-				continue
-			}
-			timer := time.NewTimer(interval)
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				return
-			case <-timer.C:
-			}
-		}
-	})
+// threadMetricName returns the name of a metric of its own for the thread called name, between prefix and suffix.
+// Every distinct thread name registers metrics that are never unregistered, so thread names must be low-cardinality,
+// e.g. not containing node numbers or addresses.
+func threadMetricName(prefix string, name string, suffix string) string {
+	return prefix + "/" + sanitizeMetricLabel(name) + suffix
 }
 
-type ThreadLauncher interface {
+// logDroppedLaunch logs that a thread wasn't launched because this was stopped,
+// at most once per droppedLaunchLogInterval with how many were dropped since the last log.
+func (s *StopWaiterSafe) logDroppedLaunch(name string) {
+	s.droppedLaunches.Add(1)
+	now := time.Now().UnixNano()
+	last := s.lastDroppedLaunchLog.Load()
+	if now-last < int64(droppedLaunchLogInterval) || !s.lastDroppedLaunchLog.CompareAndSwap(last, now) {
+		return
+	}
+	s.getLogger().Warn("thread not launched after stop", "name", s.name, "thread", name, "dropped", s.droppedLaunches.Swap(0))
+}
+
+func (s *StopWaiterSafe) registerNamedThread(name string) uint64 {
+	s.namedThreadsMutex.Lock()
+	defer s.namedThreadsMutex.Unlock()
+	if s.namedThreads == nil {
+		s.namedThreads = make(map[uint64]ThreadStatus)
+	}
+	id := s.nextThreadId
+	s.nextThreadId++
+	s.namedThreads[id] = ThreadStatus{Name: name, StartedAt: time.Now()}
+	return id
+}
+
+func (s *StopWaiterSafe) unregisterNamedThread(id uint64) {
+	s.namedThreadsMutex.Lock()
+	defer s.namedThreadsMutex.Unlock()
+	delete(s.namedThreads, id)
+}
+
+// runningNamedThreads returns the sorted names of the named threads that haven't returned yet.
+func (s *StopWaiterSafe) runningNamedThreads() []string {
+	s.namedThreadsMutex.Lock()
+	defer s.namedThreadsMutex.Unlock()
+	names := make([]string, 0, len(s.namedThreads))
+	for _, thread := range s.namedThreads {
+		names = append(names, thread.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namedThreadRunningTimes returns the still running named threads with how long they've been running, longest first.
+func (s *StopWaiterSafe) namedThreadRunningTimes() []string {
+	threads := s.ListThreads()
+	runningTimes := make([]string, 0, len(threads))
+	for _, thread := range threads {
+		runningTimes = append(runningTimes, fmt.Sprintf("%v:%v", thread.Name, thread.Running.Round(time.Millisecond)))
+	}
+	return runningTimes
+}
+
+// ThreadStatus describes a running thread launched with a name.
+type ThreadStatus struct {
+	Name      string
+	StartedAt time.Time
+	Running   time.Duration
+}
+
+// ListThreads returns the threads launched with a name that are still running, longest running first.
+func (s *StopWaiterSafe) ListThreads() []ThreadStatus {
+	s.namedThreadsMutex.Lock()
+	threads := make([]ThreadStatus, 0, len(s.namedThreads))
+	for _, thread := range s.namedThreads {
+		threads = append(threads, thread)
+	}
+	s.namedThreadsMutex.Unlock()
+	now := time.Now()
+	for i := range threads {
+		threads[i].Running = now.Sub(threads[i].StartedAt)
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].StartedAt.Before(threads[j].StartedAt)
+	})
+	return threads
+}
+
+// ActiveThreadCount returns how many launched threads haven't returned yet.
+func (s *StopWaiterSafe) ActiveThreadCount() int {
+	return int(s.activeThreads.Load())
+}
+
+// SetRecoverPanics makes threads launched after it's called recover from panics, logging them instead of
+// crashing the process. It's off by default, so a panic fails fast.
+func (s *StopWaiterSafe) SetRecoverPanics(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.recoverPanics = enabled
+}
+
+// PanicHandler is called with the name of a StopWaiter, the value one of its threads panicked with and the
+// thread's stack trace.
+type PanicHandler func(name string, recovered any, stack []byte)
+
+// SetPanicHandler sets a handler called on the panicking thread whenever a panic is recovered, after it's logged.
+// A panic in the handler itself is logged and otherwise ignored. It only has an effect with SetRecoverPanics.
+func (s *StopWaiterSafe) SetPanicHandler(handler PanicHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.panicHandler = handler
+}
+
+func (s *StopWaiterSafe) getPanicHandler() PanicHandler {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.panicHandler
+}
+
+func (s *StopWaiterSafe) getRecoverPanics() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.recoverPanics
+}
+
+// Must be deferred directly by the thread, for recover to catch its panic.
+func (s *StopWaiterSafe) recoverThreadPanic() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	threadPanicCounter.Inc(1)
+	stack := debug.Stack()
+	s.getLogger().Error("recovered panic in thread", "name", s.name, "panic", recovered, "stack", string(stack))
+	if handler := s.getPanicHandler(); handler != nil {
+		s.callPanicHandler(handler, recovered, stack)
+	}
+}
+
+func (s *StopWaiterSafe) callPanicHandler(handler PanicHandler, recovered any, stack []byte) {
+	defer func() {
+		if handlerPanic := recover(); handlerPanic != nil {
+			s.getLogger().Error("panic handler panicked", "name", s.name, "panic", handlerPanic, "stack", string(debug.Stack()))
+		}
+	}()
+	handler(s.name, recovered, stack)
+}
+
+// This calls go foo() directly, with the benefit of being easily searchable.
+// Callers may rely on the assumption that foo runs even if this is stopped.
+func (s *StopWaiterSafe) LaunchUntrackedThread(foo func()) {
+	go foo()
+}
+
+// SetMaxLimitedThreads limits how many threads launched with LaunchThreadLimitedSafe run at once.
+// If it's never called they aren't limited. It must be called before any are launched.
+func (s *StopWaiterSafe) SetMaxLimitedThreads(maxThreads int64) error {
+	if maxThreads <= 0 {
+		return fmt.Errorf("max limited threads must be positive, got %v", maxThreads)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.threadSlots = semaphore.NewWeighted(maxThreads)
+	return nil
+}
+
+// LaunchThreadLimitedSafe is LaunchThreadSafe, except that it first waits for fewer than the
+// SetMaxLimitedThreads threads it launched to be running. It returns an error wrapping ErrStopped
+// if this was stopped before the thread could be launched, including while it was waiting.
+func (s *StopWaiterSafe) LaunchThreadLimitedSafe(foo func(context.Context)) error {
+	ctx, err := s.GetContextSafe()
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	threadSlots := s.threadSlots
+	s.mutex.Unlock()
+	if threadSlots == nil {
+		return s.launchThread("", foo)
+	}
+	if err := threadSlots.Acquire(ctx, 1); err != nil {
+		return fmt.Errorf("%w while waiting to launch a thread: %w", ErrStopped, err)
+	}
+	err = s.launchThread("", func(ctx context.Context) {
+		defer threadSlots.Release(1)
+		foo(ctx)
+	})
+	if err != nil {
+		// the thread wasn't launched, so it won't release its slot
+		threadSlots.Release(1)
+	}
+	return err
+}
+
+// SetMaxThreadRestarts limits how many times each replayable thread is restarted before giving up.
+// Zero, the default, restarts them for as long as this is running.
+func (s *StopWaiterSafe) SetMaxThreadRestarts(maxRestarts int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxThreadRestarts = maxRestarts
+}
+
+func (s *StopWaiterSafe) getMaxThreadRestarts() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.maxThreadRestarts
+}
+
+// callRecovering calls foo, returning what it panicked with and where, if it did.
+func callRecovering(ctx context.Context, foo func(context.Context)) (recovered any, stack []byte) {
+	defer func() {
+		recovered = recover()
+		if recovered != nil {
+			stack = debug.Stack()
+		}
+	}()
+	foo(ctx)
+	return nil, nil
+}
+
+// LaunchReplayableThreadSafe launches foo in a thread that's restarted, with exponential backoff,
+// whenever foo panics or returns before the context is cancelled, up to SetMaxThreadRestarts times.
+// If stop was already called, thread might silently not be launched
+func (s *StopWaiterSafe) LaunchReplayableThreadSafe(foo func(context.Context)) error {
+	maxRestarts := s.getMaxThreadRestarts()
+	return s.LaunchThreadSafe(func(ctx context.Context) {
+		backoff := replayableThreadBaseBackoff
+		for restarts := 0; ; restarts++ {
+			started := time.Now()
+			recovered, stack := callRecovering(ctx, foo)
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) > replayableThreadMaxBackoff {
+				// it ran long enough that this failure is unrelated to the previous ones
+				backoff = replayableThreadBaseBackoff
+			}
+			if recovered != nil {
+				threadPanicCounter.Inc(1)
+			}
+			if maxRestarts > 0 && restarts >= maxRestarts {
+				s.getLogger().Error("replayable thread stopped, too many restarts", "name", s.name, "restarts", restarts, "panic", recovered, "stack", string(stack))
+				return
+			}
+			threadRestartCounter.Inc(1)
+			if recovered != nil {
+				s.getLogger().Error("replayable thread panicked, restarting", "name", s.name, "panic", recovered, "stack", string(stack), "backoff", backoff)
+			} else {
+				s.getLogger().Warn("replayable thread returned, restarting", "name", s.name, "backoff", backoff)
+			}
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, replayableThreadMaxBackoff)
+		}
+	})
+}
+
+// RestartMode is when a supervised thread is restarted after foo returns.
+type RestartMode int
+
+const (
+	// RestartAlways restarts foo whenever it returns.
+	RestartAlways RestartMode = iota
+	// RestartOnError restarts foo when it returns an error.
+	RestartOnError
+	// RestartNever runs foo once.
+	RestartNever
+)
+
+// RestartPolicy is how a thread launched with SuperviseSafe is restarted.
+// Restarts are delayed by a backoff starting at BaseBackoff and doubling each time up to MaxBackoff,
+// which default to one second and one minute. A MaxRestarts of zero doesn't limit restarts.
+type RestartPolicy struct {
+	Mode        RestartMode
+	MaxRestarts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// SupervisedRestartCount returns how many times the threads supervised under name have been restarted.
+func (s *StopWaiterSafe) SupervisedRestartCount(name string) int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if counter, ok := s.supervisedRestarts[name]; ok {
+		return counter.Load()
+	}
+	return 0
+}
+
+func (s *StopWaiterSafe) supervisedRestartCounter(name string) *atomic.Int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.supervisedRestarts == nil {
+		s.supervisedRestarts = make(map[string]*atomic.Int64)
+	}
+	counter, ok := s.supervisedRestarts[name]
+	if !ok {
+		counter = &atomic.Int64{}
+		s.supervisedRestarts[name] = counter
+	}
+	return counter
+}
+
+// SuperviseSafe launches foo in a thread named name, restarting it when it returns according to policy,
+// until the context is cancelled. Restarts are counted in a metric of the thread's own, so name must be
+// low-cardinality, see threadMetricName.
+// If stop was already called, thread might silently not be launched
+func (s *StopWaiterSafe) SuperviseSafe(name string, foo func(context.Context) error, policy RestartPolicy) error {
+	baseBackoff, maxBackoff := policy.BaseBackoff, policy.MaxBackoff
+	if baseBackoff <= 0 {
+		baseBackoff = replayableThreadBaseBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = replayableThreadMaxBackoff
+	}
+	restartCounter := s.supervisedRestartCounter(name)
+	restartMetric := metrics.GetOrRegisterCounter(threadMetricName("arb/stopwaiter/supervised", name, "/restart"), nil)
+	return ignoreStopped(s.launchThread(name, func(ctx context.Context) {
+		backoff := baseBackoff
+		for restarts := 0; ; restarts++ {
+			err := foo(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			restart := policy.Mode == RestartAlways || (policy.Mode == RestartOnError && err != nil)
+			if !restart {
+				s.getLogger().Info("supervised thread exited", "name", s.name, "thread", name, "err", err)
+				return
+			}
+			if policy.MaxRestarts > 0 && restarts >= policy.MaxRestarts {
+				s.getLogger().Error("supervised thread exited, too many restarts", "name", s.name, "thread", name, "restarts", restarts, "err", err)
+				return
+			}
+			restartCounter.Add(1)
+			restartMetric.Inc(1)
+			s.getLogger().Warn("supervised thread exited, restarting", "name", s.name, "thread", name, "err", err, "restarts", restarts+1, "backoff", backoff)
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}))
+}
+
+// CallIteratively calls function iteratively in a thread.
+// input param return value is how long to wait before next invocation
+// The first invocation happens immediately, see CallIterativelyWithInitialDelaySafe to wait first.
+func (s *StopWaiterSafe) CallIterativelySafe(foo func(context.Context) time.Duration) error {
+	return s.CallIterativelyWithInitialDelaySafe(foo, 0)
+}
+
+// CallIterativelyWithInitialDelaySafe is CallIterativelySafe, except that the first invocation
+// happens after initialDelay rather than immediately. A zero initialDelay calls foo immediately.
+func (s *StopWaiterSafe) CallIterativelyWithInitialDelaySafe(foo func(context.Context) time.Duration, initialDelay time.Duration) error {
+	return s.LaunchThreadSafe(func(ctx context.Context) {
+		if initialDelay > 0 {
+			timer := time.NewTimer(initialDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		for {
+			interval := foo(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if interval == time.Duration(0) {
+				continue
+			}
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// CallIterativelyNSafe is CallIterativelySafe, except that the thread exits after calling foo n times.
+// An n of zero or less calls it for as long as this is running, like CallIterativelySafe.
+func (s *StopWaiterSafe) CallIterativelyNSafe(n int, foo func(context.Context) time.Duration) error {
+	if n <= 0 {
+		return s.CallIterativelySafe(foo)
+	}
+	return s.LaunchThreadSafe(func(ctx context.Context) {
+		for i := 0; i < n; i++ {
+			interval := foo(ctx)
+			if ctx.Err() != nil || i == n-1 {
+				return
+			}
+			if interval == time.Duration(0) {
+				continue
+			}
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// CallAtFixedRateSafe calls foo in a thread every period, measured from when it's first called rather than
+// from when the previous call returned, so that slow calls don't shift later ones. If a call overruns
+// the following ticks, those are skipped and logged. The first call happens immediately.
+func (s *StopWaiterSafe) CallAtFixedRateSafe(foo func(context.Context), period time.Duration) error {
+	if period <= 0 {
+		return fmt.Errorf("fixed rate period must be positive, got %v", period)
+	}
+	return s.LaunchThreadSafe(func(ctx context.Context) {
+		next := time.Now()
+		for {
+			foo(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			next = next.Add(period)
+			if behind := time.Since(next); behind > 0 {
+				skipped := behind/period + 1
+				s.getLogger().Warn("fixed rate call overran its period, skipping ticks", "name", s.name, "period", period, "skipped", int64(skipped))
+				next = next.Add(skipped * period)
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// CallIterativelyUntilErrorSafe is CallIterativelySafe, except that the thread also exits
+// the first time foo returns an error, which is logged.
+func (s *StopWaiterSafe) CallIterativelyUntilErrorSafe(foo func(context.Context) (time.Duration, error)) error {
+	return s.LaunchThreadSafe(func(ctx context.Context) {
+		for {
+			interval, err := foo(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				s.getLogger().Error("iterative call failed, stopping it", "name", s.name, "err", err)
+				return
+			}
+			if interval == time.Duration(0) {
+				continue
+			}
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// CallIterativelyWithBackoffSafe calls foo iteratively in a thread, waiting base between calls while it succeeds.
+// Each consecutive error doubles the wait, up to maxDelay.
+func (s *StopWaiterSafe) CallIterativelyWithBackoffSafe(foo func(context.Context) error, base time.Duration, maxDelay time.Duration) error {
+	if base <= 0 || maxDelay < base {
+		return fmt.Errorf("invalid backoff: base %v, max %v", base, maxDelay)
+	}
+	delay := base
+	return s.CallIterativelySafe(func(ctx context.Context) time.Duration {
+		if err := foo(ctx); err != nil {
+			current := delay
+			delay = min(delay*2, maxDelay)
+			return current
+		}
+		delay = base
+		return delay
+	})
+}
+
+// CallIterativelyWithJitterSafe is CallIterativelySafe, except that each interval foo returns is randomly
+// adjusted by up to jitterFraction of itself either way, so that many instances don't call in lockstep.
+func (s *StopWaiterSafe) CallIterativelyWithJitterSafe(foo func(context.Context) time.Duration, jitterFraction float64) error {
+	if jitterFraction < 0 || jitterFraction > 1 {
+		return fmt.Errorf("jitter fraction must be between 0 and 1, got %v", jitterFraction)
+	}
+	return s.CallIterativelySafe(func(ctx context.Context) time.Duration {
+		return jitterInterval(foo(ctx), jitterFraction)
+	})
+}
+
+func jitterInterval(interval time.Duration, jitterFraction float64) time.Duration {
+	if interval <= 0 || jitterFraction == 0 {
+		return interval
+	}
+	// #nosec G404
+	jitter := (rand.Float64()*2 - 1) * jitterFraction * float64(interval)
+	return interval + time.Duration(jitter)
+}
+
+type ThreadLauncher interface {
 	GetContextSafe() (context.Context, error)
 	LaunchThreadSafe(foo func(context.Context)) error
 	LaunchUntrackedThread(foo func())
 	Stopped() bool
 }
 
+// StrictThreadLauncher is a ThreadLauncher that can report a thread not being launched because it was stopped,
+// for helpers that would otherwise wait forever for a thread that never ran.
+type StrictThreadLauncher interface {
+	ThreadLauncher
+	LaunchThreadStrictSafe(foo func(context.Context)) error
+}
+
 // CallIterativelyWith calls function iteratively in a thread.
 // The return value of foo is how long to wait before next invocation
 // Anything sent to triggerChan parameter triggers call to happen immediately
+// The first call happens immediately, with the zero value of T, before anything is read from triggerChan.
 func CallIterativelyWith[T any](
 	s ThreadLauncher,
 	foo func(context.Context, T) time.Duration,
 	triggerChan <-chan T,
 ) error {
+	return CallIterativelyWithPriority(s, foo, triggerChan, PreferNeither)
+}
+
+// SelectPriority is which of the timer and triggerChan CallIterativelyWithPriority services first
+// when both are ready at once.
+type SelectPriority int
+
+const (
+	// PreferNeither picks at random, as select does. Neither kind of call can be starved.
+	PreferNeither SelectPriority = iota
+	// PreferTimer makes the timed call first, then the triggered call immediately after.
+	// The periodic work is never skipped, at the cost of delaying the triggered call.
+	PreferTimer
+	// PreferTrigger makes the triggered call instead of the timed one, which is skipped.
+	// Triggers are serviced as fast as possible, at the cost of skipping periodic work under a hot trigger stream.
+	PreferTrigger
+)
+
+// CallIterativelyWithPriority is CallIterativelyWith, with priority deciding what happens when
+// the interval has passed and a value is ready on triggerChan at the same time.
+func CallIterativelyWithPriority[T any](
+	s ThreadLauncher,
+	foo func(context.Context, T) time.Duration,
+	triggerChan <-chan T,
+	priority SelectPriority,
+) error {
+	return CallIterativelyWithOptions(s, foo, triggerChan, WithSelectPriority(priority))
+}
+
+type callIterativelyConfig struct {
+	priority    SelectPriority
+	drainOnStop bool
+}
+
+type CallIterativelyOption func(*callIterativelyConfig)
+
+// WithSelectPriority sets what CallIterativelyWithOptions does when the interval has passed and
+// a value is ready on triggerChan at the same time. It defaults to PreferNeither.
+func WithSelectPriority(priority SelectPriority) CallIterativelyOption {
+	return func(c *callIterativelyConfig) {
+		c.priority = priority
+	}
+}
+
+// WithDrainOnStop makes CallIterativelyWithOptions call foo with the values already buffered in triggerChan
+// when stopping, rather than dropping them. To stop a live producer keeping it going, only as many values as
+// were buffered when the drain began are read. As the context is cancelled by then, these calls get a context
+// that isn't cancelled along with it.
+func WithDrainOnStop() CallIterativelyOption {
+	return func(c *callIterativelyConfig) {
+		c.drainOnStop = true
+	}
+}
+
+// CallIterativelyWithOptions is CallIterativelyWith, configured by opts.
+func CallIterativelyWithOptions[T any](
+	s ThreadLauncher,
+	foo func(context.Context, T) time.Duration,
+	triggerChan <-chan T,
+	opts ...CallIterativelyOption,
+) error {
+	var config callIterativelyConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
 	return s.LaunchThreadSafe(func(ctx context.Context) {
 		var defaultVal T
 		var val T
 		var ok bool
+		// pending is a triggered value held back while a timed call is made first
+		var pending T
+		var hasPending bool
+		drain := func() {
+			if !config.drainOnStop {
+				return
+			}
+			drainCtx := context.WithoutCancel(ctx)
+			if hasPending {
+				foo(drainCtx, pending)
+			}
+			for i := len(triggerChan); i > 0; i-- {
+				select {
+				case triggered, ok := <-triggerChan:
+					if !ok {
+						return
+					}
+					foo(drainCtx, triggered)
+				default:
+					return
+				}
+			}
+		}
 		for {
 			interval := foo(ctx, val)
 			if ctx.Err() != nil {
+				drain()
 				return
 			}
 			val = defaultVal
+			if hasPending {
+				val, pending, hasPending = pending, defaultVal, false
+				continue
+			}
 			if interval == time.Duration(0) {
 				continue
 			}
@@ -243,12 +1501,32 @@ func CallIterativelyWith[T any](
 			select {
 			case <-ctx.Done():
 				timer.Stop()
+				drain()
 				return
 			case <-timer.C:
+				if config.priority == PreferTrigger {
+					select {
+					case val, ok = <-triggerChan:
+						if !ok {
+							return
+						}
+					default:
+					}
+				}
 			case val, ok = <-triggerChan:
 				if !ok {
+					timer.Stop()
 					return
 				}
+				if config.priority == PreferTimer {
+					select {
+					case <-timer.C:
+						pending, hasPending = val, true
+						val = defaultVal
+					default:
+						timer.Stop()
+					}
+				}
 			}
 		}
 	})
@@ -274,10 +1552,36 @@ func CallWhenTriggeredWith[T any](
 	})
 }
 
+// CallWhenTriggeredWithTimeout is CallWhenTriggeredWith, except that each call to foo gets a context
+// that's cancelled after timeout, and calls that run past it are logged.
+func CallWhenTriggeredWithTimeout[T any](
+	s ThreadLauncher,
+	foo func(context.Context, T),
+	triggerChan <-chan T,
+	timeout time.Duration,
+) error {
+	return CallWhenTriggeredWith(s, func(ctx context.Context, val T) {
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		start := time.Now()
+		foo(callCtx, val)
+		if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			launcherLogger(s).Warn("triggered call exceeded its timeout", "timeout", timeout, "elapsed", time.Since(start))
+		}
+	}, triggerChan)
+}
+
 func LaunchPromiseThread[T any](
 	s ThreadLauncher,
 	foo func(context.Context) (T, error),
 ) containers.PromiseInterface[T] {
+	return Go(s, foo)
+}
+
+// Go runs foo in a thread launched on s, returning a promise of its result, like errgroup's Go but for a single value.
+// The thread is tracked by s, so stopping s cancels foo's context and waits for it to return.
+// If s isn't running, the promise produces an error instead.
+func Go[T any](s ThreadLauncher, foo func(context.Context) (T, error)) *containers.Promise[T] {
 	ctx, err := s.GetContextSafe()
 	if err != nil {
 		promise := containers.NewPromise[T](nil)
@@ -306,20 +1610,441 @@ func LaunchPromiseThread[T any](
 	return &promise
 }
 
-func ChanRateLimiter[T any](s *StopWaiterSafe, inChan <-chan T, maxRateCallback func() time.Duration) (<-chan T, error) {
-	outChan := make(chan T)
+// PromiseCache holds the promises of LaunchPromiseThreadCached by key. The zero value is ready to use.
+type PromiseCache[K comparable, T any] struct {
+	mutex    sync.Mutex
+	promises map[K]*containers.Promise[T]
+}
+
+// Invalidate makes the next LaunchPromiseThreadCached call for key compute its value again.
+// Promises already returned for key are unaffected.
+func (c *PromiseCache[K, T]) Invalidate(key K) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.promises, key)
+}
+
+// InvalidateAll is Invalidate for every key.
+func (c *PromiseCache[K, T]) InvalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.promises = nil
+}
+
+// forget drops promise from the cache, unless key has already been invalidated and computed again.
+func (c *PromiseCache[K, T]) forget(key K, promise *containers.Promise[T]) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.promises[key] == promise {
+		delete(c.promises, key)
+	}
+}
+
+// LaunchPromiseThreadCached is LaunchPromiseThread, except that it returns the same promise for the same key
+// until the key is invalidated in cache, so foo is only run once however many callers want its value at once.
+// Errors aren't cached: a call after foo fails runs it again.
+// As the promise is shared, a caller giving up on awaiting it doesn't cancel foo; only stopping s does.
+func LaunchPromiseThreadCached[K comparable, T any](
+	s StrictThreadLauncher,
+	cache *PromiseCache[K, T],
+	key K,
+	foo func(context.Context) (T, error),
+) containers.PromiseInterface[T] {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if promise, ok := cache.promises[key]; ok {
+		return promise
+	}
+	promise := containers.NewPromise[T](nil)
+	err := s.LaunchThreadStrictSafe(func(ctx context.Context) {
+		val, err := foo(ctx)
+		if err != nil {
+			cache.forget(key, &promise)
+			promise.ProduceError(err)
+		} else {
+			promise.Produce(val)
+		}
+	})
+	if err != nil {
+		promise.ProduceError(err)
+		return &promise
+	}
+	if cache.promises == nil {
+		cache.promises = make(map[K]*containers.Promise[T])
+	}
+	cache.promises[key] = &promise
+	return &promise
+}
+
+// ErrPromiseTimeout is produced by a promise from LaunchPromiseThreadWithTimeout whose function didn't return in time.
+var ErrPromiseTimeout = errors.New("promise timed out")
+
+// LaunchPromiseThreadWithTimeout is LaunchPromiseThread, except that foo's context is cancelled after timeout,
+// at which point the promise produces ErrPromiseTimeout even if foo hasn't returned.
+func LaunchPromiseThreadWithTimeout[T any](
+	s ThreadLauncher,
+	foo func(context.Context) (T, error),
+	timeout time.Duration,
+) containers.PromiseInterface[T] {
+	ctx, err := s.GetContextSafe()
+	if err != nil {
+		promise := containers.NewPromise[T](nil)
+		promise.ProduceError(err)
+		return &promise
+	}
+	if s.Stopped() {
+		promise := containers.NewPromise[T](nil)
+		promise.ProduceError(errors.New("stopped"))
+		return &promise
+	}
+	timeoutErr := fmt.Errorf("%w after %v", ErrPromiseTimeout, timeout)
+	innerCtx, cancel := context.WithTimeoutCause(ctx, timeout, timeoutErr)
+	promise := containers.NewPromise[T](cancel)
+	timedOut := func() bool {
+		return errors.Is(context.Cause(innerCtx), ErrPromiseTimeout)
+	}
+	stopTimeout := context.AfterFunc(innerCtx, func() {
+		if timedOut() {
+			// foo might not return for a while, if ever, so don't wait for it
+			_ = promise.ProduceErrorSafe(timeoutErr)
+		}
+	})
+	err = s.LaunchThreadSafe(func(context.Context) { // we don't use the param's context
+		val, err := foo(innerCtx)
+		stopTimeout()
+		// the promise may already have been produced on timeout
+		if err != nil && timedOut() {
+			_ = promise.ProduceErrorSafe(timeoutErr)
+		} else if err != nil {
+			_ = promise.ProduceErrorSafe(err)
+		} else {
+			_ = promise.ProduceSafe(val)
+		}
+		cancel()
+	})
+	if err != nil {
+		_ = promise.ProduceErrorSafe(err)
+		cancel()
+	}
+	return &promise
+}
+
+// WaitAllPromises waits for all of promises, returning their results in the same order.
+// If one of them fails, or ctx is done, the others are cancelled and the error is returned right away.
+func WaitAllPromises[T any](ctx context.Context, promises ...containers.PromiseInterface[T]) ([]T, error) {
+	done := make(chan struct{})
+	defer close(done)
+	ready := make(chan int, len(promises))
+	for i, promise := range promises {
+		go func() {
+			select {
+			case <-promise.ReadyChan():
+				ready <- i
+			case <-ctx.Done():
+			case <-done:
+			}
+		}()
+	}
+	cancelAll := func() {
+		for _, promise := range promises {
+			promise.Cancel()
+		}
+	}
+	results := make([]T, len(promises))
+	for range promises {
+		select {
+		case i := <-ready:
+			result, err := promises[i].Current()
+			if err != nil {
+				cancelAll()
+				return nil, err
+			}
+			results[i] = result
+		case <-ctx.Done():
+			cancelAll()
+			return nil, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+// ThreadGroup launches threads that can fail, and waits for them to collect their errors.
+type ThreadGroup struct {
+	launcher StrictThreadLauncher
+	wg       sync.WaitGroup
+	mutex    sync.Mutex // protects errs
+	errs     []error
+}
+
+// NewThreadGroup creates a ThreadGroup whose threads are launched with, and tracked by, launcher.
+func NewThreadGroup(launcher StrictThreadLauncher) *ThreadGroup {
+	return &ThreadGroup{launcher: launcher}
+}
+
+func (g *ThreadGroup) addError(err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.errs = append(g.errs, err)
+}
+
+// Launch launches foo in a thread of the group. Failing to launch it counts as an error of the group.
+func (g *ThreadGroup) Launch(foo func(context.Context) error) {
+	g.wg.Add(1)
+	err := g.launcher.LaunchThreadStrictSafe(func(ctx context.Context) {
+		defer g.wg.Done()
+		if err := foo(ctx); err != nil {
+			g.addError(err)
+		}
+	})
+	if err != nil {
+		g.wg.Done()
+		g.addError(err)
+	}
+}
+
+// Wait waits for all the threads launched so far to return, or for the launcher's context to be done,
+// and returns their errors joined together.
+func (g *ThreadGroup) Wait() error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	var ctxErr error
+	ctx, err := g.launcher.GetContextSafe()
+	if err != nil {
+		<-done
+	} else {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			select {
+			case <-done:
+			default:
+				ctxErr = ctx.Err()
+			}
+		}
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return errors.Join(append(append([]error{}, g.errs...), ctxErr)...)
+}
+
+// ParallelMap calls fn on each of items in threads launched with s, at most concurrency at once,
+// and returns the results in the same order. The first error cancels the context of the other calls,
+// and is returned once they've all returned. A concurrency of zero or less calls fn on all items at once.
+func ParallelMap[T, R any](
+	s StrictThreadLauncher,
+	items []T,
+	concurrency int,
+	fn func(context.Context, T) (R, error),
+) ([]R, error) {
+	ctx, err := s.GetContextSafe()
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 || concurrency > len(items) {
+		concurrency = len(items)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]R, len(items))
+	var firstErr error
+	var failOnce sync.Once
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		err := s.LaunchThreadStrictSafe(func(context.Context) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := fn(ctx, item)
+			if err != nil {
+				fail(err)
+				return
+			}
+			results[i] = result
+		})
+		if err != nil {
+			wg.Done()
+			<-slots
+			fail(err)
+			break
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type chanRateLimiterConfig struct {
+	burst        int
+	keepLatest   bool
+	outputBuffer int
+	rateChan     <-chan time.Duration
+}
+
+type ChanRateLimiterOption func(*chanRateLimiterConfig)
+
+// WithRateLimiterBurst lets up to burst items through at once, as a token bucket refilled with one token
+// per maxRateCallback interval, up to burst tokens. Without it, items arriving within an interval of the
+// last one let through are dropped.
+func WithRateLimiterBurst(burst int) ChanRateLimiterOption {
+	return func(c *chanRateLimiterConfig) {
+		c.burst = burst
+	}
+}
+
+// WithRateLimiterKeepLatest holds on to the latest item that would have been dropped, and lets it through
+// as soon as the rate allows, so the last item sent to the limiter is always delivered.
+func WithRateLimiterKeepLatest() ChanRateLimiterOption {
+	return func(c *chanRateLimiterConfig) {
+		c.keepLatest = true
+	}
+}
+
+// WithRateLimiterOutputBuffer buffers up to size items let through, so a slow consumer doesn't hold up
+// the limiter. When the limiter stops, the output channel is closed but items already buffered can
+// still be received from it.
+func WithRateLimiterOutputBuffer(size int) ChanRateLimiterOption {
+	return func(c *chanRateLimiterConfig) {
+		c.outputBuffer = size
+	}
+}
+
+// WithRateLimiterRateChan overrides maxRateCallback with the latest interval received from rateChan,
+// from the next time the interval is needed. Intervals that aren't positive are ignored.
+func WithRateLimiterRateChan(rateChan <-chan time.Duration) ChanRateLimiterOption {
+	return func(c *chanRateLimiterConfig) {
+		c.rateChan = rateChan
+	}
+}
+
+// tokenBucket allows an average of one event per interval, with bursts of up to burst events.
+type tokenBucket struct {
+	burst      float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{burst: float64(burst), tokens: float64(burst), lastRefill: now}
+}
+
+// allow takes a token if there is one, otherwise it returns how long until there will be.
+func (b *tokenBucket) allow(now time.Time, interval time.Duration) (bool, time.Duration) {
+	if interval <= 0 {
+		return true, 0
+	}
+	b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.lastRefill))/float64(interval))
+	b.lastRefill = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func ChanRateLimiter[T any](s *StopWaiterSafe, inChan <-chan T, maxRateCallback func() time.Duration, opts ...ChanRateLimiterOption) (<-chan T, error) {
+	var config chanRateLimiterConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.burst < 0 {
+		return nil, fmt.Errorf("rate limiter burst must not be negative, got %v", config.burst)
+	}
+	if config.outputBuffer < 0 {
+		return nil, fmt.Errorf("rate limiter output buffer must not be negative, got %v", config.outputBuffer)
+	}
+	outChan := make(chan T, config.outputBuffer)
 	err := s.LaunchThreadSafe(func(ctx context.Context) {
-		nextAllowedTriggerTime := time.Now()
+		rateChan := config.rateChan
+		var pushedInterval time.Duration
+		interval := func() time.Duration {
+			if pushedInterval > 0 {
+				return pushedInterval
+			}
+			return maxRateCallback()
+		}
+		// allow returns whether an item can be let through now, and if not how long until one can
+		var allow func(now time.Time) (bool, time.Duration)
+		if config.burst > 0 {
+			bucket := newTokenBucket(config.burst, time.Now())
+			allow = func(now time.Time) (bool, time.Duration) {
+				return bucket.allow(now, interval())
+			}
+		} else {
+			nextAllowedTriggerTime := time.Now()
+			allow = func(now time.Time) (bool, time.Duration) {
+				if now.After(nextAllowedTriggerTime) {
+					nextAllowedTriggerTime = now.Add(interval())
+					return true, 0
+				}
+				return false, nextAllowedTriggerTime.Sub(now)
+			}
+		}
+		var latest T
+		var retryTimer *time.Timer
+		var retryChan <-chan time.Time
+		stopRetry := func() {
+			if retryTimer != nil {
+				retryTimer.Stop()
+			}
+			retryTimer, retryChan = nil, nil
+		}
 		for {
 			select {
 			case <-ctx.Done():
+				stopRetry()
 				close(outChan)
 				return
 			case data := <-inChan:
-				now := time.Now()
-				if now.After(nextAllowedTriggerTime) {
+				allowed, wait := allow(time.Now())
+				if allowed {
+					// this supersedes any held item
+					stopRetry()
 					outChan <- data
-					nextAllowedTriggerTime = now.Add(maxRateCallback())
+				} else if config.keepLatest {
+					latest = data
+					if retryChan == nil {
+						retryTimer = time.NewTimer(wait)
+						retryChan = retryTimer.C
+					}
+				}
+			case rate, ok := <-rateChan:
+				if !ok {
+					rateChan = nil
+				} else if rate > 0 {
+					pushedInterval = rate
+				}
+			case <-retryChan:
+				retryTimer, retryChan = nil, nil
+				allowed, wait := allow(time.Now())
+				if allowed {
+					outChan <- latest
+					var zero T
+					latest = zero
+				} else {
+					retryTimer = time.NewTimer(wait)
+					retryChan = retryTimer.C
 				}
 			}
 		}
@@ -343,6 +2068,14 @@ func (s *StopWaiter) Start(ctx context.Context, parent any) {
 	}
 }
 
+func (s *StopWaiter) StartOnce(ctx context.Context, parent any) context.Context {
+	ctx, err := s.StopWaiterSafe.StartOnce(ctx, parent)
+	if err != nil {
+		panic(err)
+	}
+	return ctx
+}
+
 func (s *StopWaiter) StopAndWait() {
 	if err := s.StopWaiterSafe.StopAndWait(); err != nil {
 		panic(err)
@@ -356,12 +2089,79 @@ func (s *StopWaiter) LaunchThread(foo func(context.Context)) {
 	}
 }
 
+// If stop was already called, thread might silently not be launched
+func (s *StopWaiter) LaunchThreadWithName(name string, foo func(context.Context)) {
+	if err := s.StopWaiterSafe.LaunchThreadWithNameSafe(name, foo); err != nil {
+		panic(err)
+	}
+}
+
+// If stop was already called, thread might silently not be launched
+func (s *StopWaiter) LaunchReplayableThread(foo func(context.Context)) {
+	if err := s.StopWaiterSafe.LaunchReplayableThreadSafe(foo); err != nil {
+		panic(err)
+	}
+}
+
+// Unlike the other StopWaiter methods this returns its error, as stopping while waiting to launch is expected.
+func (s *StopWaiter) LaunchThreadLimited(foo func(context.Context)) error {
+	return s.StopWaiterSafe.LaunchThreadLimitedSafe(foo)
+}
+
+// Unlike the other StopWaiter methods this returns its error, as being stopped is expected.
+func (s *StopWaiter) LaunchThreadStrict(foo func(context.Context)) error {
+	return s.StopWaiterSafe.LaunchThreadStrictSafe(foo)
+}
+
 func (s *StopWaiter) CallIteratively(foo func(context.Context) time.Duration) {
 	if err := s.StopWaiterSafe.CallIterativelySafe(foo); err != nil {
 		panic(err)
 	}
 }
 
+func (s *StopWaiter) CallIterativelyWithInitialDelay(foo func(context.Context) time.Duration, initialDelay time.Duration) {
+	if err := s.StopWaiterSafe.CallIterativelyWithInitialDelaySafe(foo, initialDelay); err != nil {
+		panic(err)
+	}
+}
+
+// If stop was already called, thread might silently not be launched
+func (s *StopWaiter) Supervise(name string, foo func(context.Context) error, policy RestartPolicy) {
+	if err := s.StopWaiterSafe.SuperviseSafe(name, foo, policy); err != nil {
+		panic(err)
+	}
+}
+
+func (s *StopWaiter) CallIterativelyN(n int, foo func(context.Context) time.Duration) {
+	if err := s.StopWaiterSafe.CallIterativelyNSafe(n, foo); err != nil {
+		panic(err)
+	}
+}
+
+func (s *StopWaiter) CallAtFixedRate(foo func(context.Context), period time.Duration) {
+	if err := s.StopWaiterSafe.CallAtFixedRateSafe(foo, period); err != nil {
+		panic(err)
+	}
+}
+
+func (s *StopWaiter) CallIterativelyUntilError(foo func(context.Context) (time.Duration, error)) {
+	if err := s.StopWaiterSafe.CallIterativelyUntilErrorSafe(foo); err != nil {
+		panic(err)
+	}
+}
+
+func (s *StopWaiter) CallIterativelyWithBackoff(foo func(context.Context) error, base time.Duration, maxDelay time.Duration) {
+	if err := s.StopWaiterSafe.CallIterativelyWithBackoffSafe(foo, base, maxDelay); err != nil {
+		panic(err)
+	}
+}
+
+func (s *StopWaiter) CallIterativelyWithJitter(foo func(context.Context) time.Duration, jitterFraction float64) {
+	if err := s.StopWaiterSafe.CallIterativelyWithJitterSafe(foo, jitterFraction); err != nil {
+		panic(err)
+	}
+}
+
 func (s *StopWaiter) GetContext() context.Context {
 	ctx, err := s.StopWaiterSafe.GetContextSafe()
 	if err != nil {
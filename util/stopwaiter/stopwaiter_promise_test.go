@@ -2,6 +2,8 @@ package stopwaiter
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -98,7 +100,132 @@ func TestStopWaiterPromise(t *testing.T) {
 	}
 }
 
+func TestStopWaiterPromiseTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	classA := &ClassA{}
+	classA.Start(ctx)
+	defer classA.StopAndWait()
+
+	promise := LaunchPromiseThreadWithTimeout[uint64](classA, func(ctx context.Context) (uint64, error) {
+		return classA.longFunc(ctx, time.Minute)
+	}, time.Millisecond*50)
+	_, err := promise.Await(ctx)
+	if !errors.Is(err, ErrPromiseTimeout) {
+		t.Fatal("expected a promise timeout, got", err)
+	}
+
+	// a function that ignores its context still times out
+	start := time.Now()
+	promise = LaunchPromiseThreadWithTimeout[uint64](classA, func(context.Context) (uint64, error) {
+		time.Sleep(time.Millisecond * 500)
+		return 42, nil
+	}, time.Millisecond*50)
+	_, err = promise.Await(ctx)
+	if !errors.Is(err, ErrPromiseTimeout) {
+		t.Fatal("expected a promise timeout, got", err)
+	}
+	if time.Since(start) >= time.Millisecond*500 {
+		t.Fatal("promise waited for its function to return after timing out")
+	}
+
+	promise = LaunchPromiseThreadWithTimeout[uint64](classA, func(ctx context.Context) (uint64, error) {
+		return classA.longFunc(ctx, time.Millisecond)
+	}, time.Minute)
+	val, err := promise.Await(ctx)
+	Require(t, err)
+	if val != 42 {
+		t.Fatal("expected 42, got", val)
+	}
+}
+
+func TestWaitAllPromises(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	classA := &ClassA{}
+	classA.Start(ctx)
+	defer classA.StopAndWait()
+
+	results, err := WaitAllPromises(ctx, classA.LongFunc(time.Millisecond*10), classA.ShortFunc(), classA.LongFunc(time.Millisecond))
+	Require(t, err)
+	if len(results) != 3 || results[0] != 42 || results[1] != 42 || results[2] != 42 {
+		t.Fatal("unexpected results", results)
+	}
+
+	errFailed := errors.New("failed")
+	failing := LaunchPromiseThread[uint64](classA, func(ctx context.Context) (uint64, error) {
+		return 0, errFailed
+	})
+	slow := []containers.PromiseInterface[uint64]{classA.LongFunc(time.Minute), classA.LongFunc(time.Minute)}
+	start := time.Now()
+	_, err = WaitAllPromises(ctx, slow[0], failing, slow[1])
+	if !errors.Is(err, errFailed) {
+		t.Fatal("expected the failing promise's error, got", err)
+	}
+	if time.Since(start) >= time.Minute {
+		t.Fatal("waited for the other promises after one failed")
+	}
+	for i, promise := range slow {
+		_, err := promise.Await(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("expected promise", i, "to be cancelled, got", err)
+		}
+	}
+}
+
 func Require(t *testing.T, err error, printables ...interface{}) {
 	t.Helper()
 	testhelpers.RequireImpl(t, err, printables...)
 }
+
+func TestLaunchPromiseThreadCached(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	classA := &ClassA{}
+	classA.Start(ctx)
+	defer classA.StopAndWait()
+
+	var cache PromiseCache[string, uint64]
+	var calls atomic.Int64
+	compute := func(ctx context.Context) (uint64, error) {
+		calls.Add(1)
+		return classA.longFunc(ctx, time.Millisecond*50)
+	}
+	promises := make([]containers.PromiseInterface[uint64], 5)
+	for i := range promises {
+		promises[i] = LaunchPromiseThreadCached(classA, &cache, "config", compute)
+	}
+	_, err := WaitAllPromises(ctx, promises...)
+	Require(t, err)
+	if calls.Load() != 1 {
+		t.Fatal("expected one computation, got", calls.Load())
+	}
+
+	// an awaiter giving up doesn't cancel the shared computation
+	cache.Invalidate("config")
+	promise := LaunchPromiseThreadCached(classA, &cache, "config", compute)
+	shortCtx, shortCancel := context.WithTimeout(ctx, time.Millisecond)
+	_, err = promise.Await(shortCtx)
+	shortCancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected the short await to time out, got", err)
+	}
+	_, err = LaunchPromiseThreadCached(classA, &cache, "config", compute).Await(ctx)
+	Require(t, err)
+	if calls.Load() != 2 {
+		t.Fatal("expected a computation after invalidation, got", calls.Load())
+	}
+
+	errFailed := errors.New("failed")
+	_, err = LaunchPromiseThreadCached(classA, &cache, "failing", func(context.Context) (uint64, error) {
+		return 0, errFailed
+	}).Await(ctx)
+	if !errors.Is(err, errFailed) {
+		t.Fatal("expected the computation's error, got", err)
+	}
+	_, err = LaunchPromiseThreadCached(classA, &cache, "failing", compute).Await(ctx)
+	Require(t, err)
+}
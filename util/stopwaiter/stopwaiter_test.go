@@ -5,11 +5,14 @@ package stopwaiter
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 
 	"github.com/offchainlabs/nitro/util/testhelpers"
 )
@@ -37,6 +40,29 @@ func TestStopWaiterStopAndWaitTimeoutShouldWarn(t *testing.T) {
 	}
 }
 
+func TestStopWaiterStopAndWaitRepeatsWarning(t *testing.T) {
+	logHandler := testhelpers.InitTestLog(t, log.LvlTrace)
+	sw := StopWaiter{}
+	sw.SetRepeatStopWarnings(true)
+	testCtx, cancel := context.WithCancel(context.Background())
+	sw.Start(context.Background(), &TestStruct{})
+	sw.LaunchThreadWithName("stuck", func(ctx context.Context) {
+		<-testCtx.Done()
+	})
+	stopErr := make(chan error, 1)
+	go func() {
+		stopErr <- sw.stopAndWaitImpl(testStopDelayWarningTimeout)
+	}()
+	time.Sleep(2*testStopDelayWarningTimeout + 100*time.Millisecond)
+	repeated := logHandler.WasLogged("still taking too long to stop")
+	// release the stuck thread, and wait for stopping to finish before the test returns
+	cancel()
+	testhelpers.RequireImpl(t, <-stopErr)
+	if !repeated {
+		testhelpers.FailImpl(t, "Failed to repeat the warning about waiting long on StopAndWait")
+	}
+}
+
 func TestStopWaiterStopAndWaitTimeoutShouldNotWarn(t *testing.T) {
 	logHandler := testhelpers.InitTestLog(t, log.LvlTrace)
 	sw := StopWaiter{}
@@ -90,3 +116,363 @@ func TestStopWaiterStopOnlyThenStopAndWait(t *testing.T) {
 		t.Error("StopAndWait returned before background thread stopped")
 	}
 }
+
+func TestStopWaiterRecoversThreadPanic(t *testing.T) {
+	logHandler := testhelpers.InitTestLog(t, log.LvlTrace)
+	sw := StopWaiter{}
+	sw.SetRecoverPanics(true)
+	sw.Start(context.Background(), &TestStruct{})
+	panicked := make(chan struct{})
+	sw.LaunchThread(func(context.Context) {
+		close(panicked)
+		panic("test panic")
+	})
+	<-panicked
+	var survived atomic.Bool
+	sw.LaunchThread(func(ctx context.Context) {
+		<-ctx.Done()
+		survived.Store(true)
+	})
+	sw.StopAndWait()
+	if !survived.Load() {
+		testhelpers.FailImpl(t, "thread launched after the panic didn't run to completion")
+	}
+	if !logHandler.WasLogged("recovered panic in thread") {
+		testhelpers.FailImpl(t, "Failed to log the recovered panic")
+	}
+	if count := sw.ActiveThreadCount(); count != 0 {
+		testhelpers.FailImpl(t, "expected no active threads after StopAndWait, got", count)
+	}
+}
+
+func TestStopWaiterPanicHandler(t *testing.T) {
+	sw := StopWaiter{}
+	sw.SetRecoverPanics(true)
+	var handled atomic.Int32
+	sw.SetPanicHandler(func(name string, recovered any, stack []byte) {
+		if name != "stopwaiter.TestStruct" || recovered != "test panic" || len(stack) == 0 {
+			t.Error("unexpected panic handler arguments", name, recovered, len(stack))
+		}
+		handled.Add(1)
+		panic("handler panic")
+	})
+	sw.Start(context.Background(), &TestStruct{})
+	sw.LaunchThread(func(context.Context) {
+		panic("test panic")
+	})
+	sw.StopAndWait()
+	if handled.Load() != 1 {
+		testhelpers.FailImpl(t, "expected the panic handler to be called once, got", handled.Load())
+	}
+}
+
+func TestStopWaiterRestart(t *testing.T) {
+	sw := StopWaiter{}
+	sw.SetAllowRestart(true)
+	for i := 0; i < 3; i++ {
+		sw.Start(context.Background(), &TestStruct{})
+		if sw.Stopped() {
+			testhelpers.FailImpl(t, "StopWaiter still stopped after restart", i)
+		}
+		var threadStopped atomic.Bool
+		sw.LaunchThread(func(ctx context.Context) {
+			<-ctx.Done()
+			threadStopped.Store(true)
+		})
+		sw.StopAndWait()
+		if !threadStopped.Load() {
+			testhelpers.FailImpl(t, "StopAndWait returned before thread stopped after restart", i)
+		}
+	}
+}
+
+func TestStopWaiterRestartNotAllowed(t *testing.T) {
+	sw := StopWaiterSafe{}
+	testhelpers.RequireImpl(t, sw.Start(context.Background(), &TestStruct{}))
+	testhelpers.RequireImpl(t, sw.StopAndWait())
+	if err := sw.Start(context.Background(), &TestStruct{}); err == nil {
+		testhelpers.FailImpl(t, "expected restart to fail without SetAllowRestart")
+	}
+}
+
+func TestStopWaiterStartOnce(t *testing.T) {
+	sw := StopWaiter{}
+	ctxs := make([]context.Context, 8)
+	var wg sync.WaitGroup
+	for i := range ctxs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctxs[i] = sw.StartOnce(context.Background(), &TestStruct{})
+		}()
+	}
+	wg.Wait()
+	for i, ctx := range ctxs {
+		if ctx != sw.GetContext() {
+			testhelpers.FailImpl(t, "StartOnce returned a different context", i)
+		}
+	}
+	sw.StopAndWait()
+}
+
+func TestStopWaiterStartOnceRestart(t *testing.T) {
+	sw := StopWaiterSafe{}
+	sw.SetAllowRestart(true)
+	firstCtx, err := sw.StartOnce(context.Background(), &TestStruct{})
+	testhelpers.RequireImpl(t, err)
+	testhelpers.RequireImpl(t, sw.StopAndWait())
+	secondCtx, err := sw.StartOnce(context.Background(), &TestStruct{})
+	testhelpers.RequireImpl(t, err)
+	if secondCtx == firstCtx || secondCtx.Err() != nil {
+		testhelpers.FailImpl(t, "StartOnce didn't restart a stopped StopWaiter allowing restarts")
+	}
+	againCtx, err := sw.StartOnce(context.Background(), &TestStruct{})
+	testhelpers.RequireImpl(t, err)
+	if againCtx != secondCtx {
+		testhelpers.FailImpl(t, "StartOnce restarted a running StopWaiter")
+	}
+	testhelpers.RequireImpl(t, sw.StopAndWait())
+
+	// without restarts allowed, the stopped context is returned
+	sw = StopWaiterSafe{}
+	_, err = sw.StartOnce(context.Background(), &TestStruct{})
+	testhelpers.RequireImpl(t, err)
+	testhelpers.RequireImpl(t, sw.StopAndWait())
+	stoppedCtx, err := sw.StartOnce(context.Background(), &TestStruct{})
+	testhelpers.RequireImpl(t, err)
+	if stoppedCtx.Err() == nil {
+		testhelpers.FailImpl(t, "StartOnce restarted a StopWaiter not allowing restarts")
+	}
+}
+
+func TestStopWaiterStartOnceRetriesAfterFailure(t *testing.T) {
+	parent := StopWaiterSafe{}
+	child := StopWaiterSafe{}
+	testhelpers.RequireImpl(t, parent.AddChild(&child))
+	if _, err := child.StartOnce(context.Background(), &TestStruct{}); err == nil {
+		testhelpers.FailImpl(t, "expected StartOnce to fail before the parent started")
+	}
+	testhelpers.RequireImpl(t, parent.Start(context.Background(), &TestStruct{}))
+	ctx, err := child.StartOnce(context.Background(), &TestStruct{})
+	testhelpers.RequireImpl(t, err)
+	if ctx.Err() != nil {
+		testhelpers.FailImpl(t, "child context cancelled after StartOnce")
+	}
+	testhelpers.RequireImpl(t, parent.StopAndWait())
+}
+
+func TestStopWaiterLaunchThreadLimitedAfterStop(t *testing.T) {
+	sw := StopWaiterSafe{}
+	testhelpers.RequireImpl(t, sw.SetMaxLimitedThreads(1))
+	testhelpers.RequireImpl(t, sw.Start(context.Background(), &TestStruct{}))
+	sw.StopOnly()
+	if err := sw.LaunchThreadLimitedSafe(func(context.Context) {}); !errors.Is(err, ErrStopped) {
+		testhelpers.FailImpl(t, "expected ErrStopped launching a limited thread after stop, got", err)
+	}
+	if !sw.threadSlots.TryAcquire(1) {
+		testhelpers.FailImpl(t, "thread slot leaked by a limited thread that wasn't launched")
+	}
+	testhelpers.RequireImpl(t, sw.StopAndWait())
+}
+
+func TestStopWaiterChildrenStopBeforeParent(t *testing.T) {
+	parent := StopWaiter{}
+	parent.Start(context.Background(), &TestStruct{})
+	var threadsStopped atomic.Int32
+	children := make([]*StopWaiter, 3)
+	for i := range children {
+		child := &StopWaiter{}
+		testhelpers.RequireImpl(t, parent.AddChild(&child.StopWaiterSafe))
+		child.Start(context.Background(), &TestStruct{})
+		child.LaunchThread(func(ctx context.Context) {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			threadsStopped.Add(1)
+		})
+		children[i] = child
+	}
+	parent.StopAndWait()
+	if threadsStopped.Load() != int32(len(children)) {
+		testhelpers.FailImpl(t, "parent StopAndWait returned before its children's threads stopped")
+	}
+	for i, child := range children {
+		if !child.Stopped() {
+			testhelpers.FailImpl(t, "child", i, "not stopped after parent StopAndWait")
+		}
+	}
+}
+
+func TestStopWaiterChildrenStopInPriorityOrder(t *testing.T) {
+	parent := StopWaiter{}
+	parent.Start(context.Background(), &TestStruct{})
+	priorities := []int{1, 3, DefaultChildStopPriority, 2, 3, 1, 2}
+	// stoppedThreads counts the threads of each priority that have returned
+	stoppedThreads := make(map[int]*atomic.Int32)
+	countByPriority := make(map[int]int32)
+	for _, priority := range priorities {
+		stoppedThreads[priority] = &atomic.Int32{}
+		countByPriority[priority]++
+	}
+	drained := func(above int) bool {
+		for other, stopped := range stoppedThreads {
+			if other > above && stopped.Load() != countByPriority[other] {
+				return false
+			}
+		}
+		return true
+	}
+	var stopOrderMutex sync.Mutex
+	var stopOrder []int
+	for _, priority := range priorities {
+		child := &StopWaiter{}
+		testhelpers.RequireImpl(t, parent.AddChildWithPriority(&child.StopWaiterSafe, priority))
+		child.Start(context.Background(), &TestStruct{})
+		child.LaunchThread(func(ctx context.Context) {
+			<-ctx.Done()
+			stopOrderMutex.Lock()
+			stopOrder = append(stopOrder, priority)
+			if !drained(priority) {
+				t.Error("child with priority", priority, "cancelled before higher priorities drained")
+			}
+			stopOrderMutex.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			stoppedThreads[priority].Add(1)
+		})
+	}
+	var parentDrainedLast atomic.Bool
+	parent.LaunchThread(func(ctx context.Context) {
+		<-ctx.Done()
+		parentDrainedLast.Store(drained(DefaultChildStopPriority - 1))
+	})
+	parent.StopAndWait()
+	if len(stopOrder) != len(priorities) {
+		testhelpers.FailImpl(t, "expected", len(priorities), "children to stop, got", len(stopOrder))
+	}
+	for i := 1; i < len(stopOrder); i++ {
+		if stopOrder[i] > stopOrder[i-1] {
+			testhelpers.FailImpl(t, "expected children to stop in decreasing priority order, got", stopOrder)
+		}
+	}
+	if !parentDrainedLast.Load() {
+		testhelpers.FailImpl(t, "parent context cancelled before its children drained")
+	}
+}
+
+func TestStopWaiterChildContextDerivesFromParent(t *testing.T) {
+	parent := StopWaiter{}
+	child := StopWaiter{}
+	testhelpers.RequireImpl(t, parent.AddChild(&child.StopWaiterSafe))
+	if err := child.StopWaiterSafe.Start(context.Background(), &TestStruct{}); err == nil {
+		testhelpers.FailImpl(t, "expected child to fail to start before its parent")
+	}
+	parent.Start(context.Background(), &TestStruct{})
+	child.Start(context.Background(), &TestStruct{})
+	parent.StopOnly()
+	<-child.GetContext().Done()
+	parent.StopAndWait()
+
+	// cancelling the parent's context from outside cancels the child's too
+	parentCtx, cancel := context.WithCancel(context.Background())
+	parent = StopWaiter{}
+	child = StopWaiter{}
+	testhelpers.RequireImpl(t, parent.AddChild(&child.StopWaiterSafe))
+	parent.Start(parentCtx, &TestStruct{})
+	child.Start(context.Background(), &TestStruct{})
+	cancel()
+	<-child.GetContext().Done()
+	parent.StopAndWait()
+}
+
+func TestStopWaiterCallIterativelyUntilError(t *testing.T) {
+	logHandler := testhelpers.InitTestLog(t, log.LvlTrace)
+	sw := StopWaiter{}
+	sw.Start(context.Background(), &TestStruct{})
+	var calls atomic.Int32
+	sw.CallIterativelyUntilError(func(context.Context) (time.Duration, error) {
+		if calls.Add(1) == 3 {
+			return 0, errors.New("unrecoverable")
+		}
+		return time.Millisecond, nil
+	})
+	waitChan := make(chan struct{})
+	go func() {
+		defer close(waitChan)
+		for sw.ActiveThreadCount() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	select {
+	case <-waitChan:
+	case <-time.After(5 * time.Second):
+		testhelpers.FailImpl(t, "loop didn't end after foo returned an error")
+	}
+	if calls.Load() != 3 {
+		testhelpers.FailImpl(t, "expected 3 calls, got", calls.Load())
+	}
+	if !logHandler.WasLogged("iterative call failed") {
+		testhelpers.FailImpl(t, "Failed to log the error ending the loop")
+	}
+	if sw.Stopped() {
+		testhelpers.FailImpl(t, "an error ending the loop shouldn't stop the StopWaiter")
+	}
+	sw.StopAndWait()
+}
+
+func TestStopWaiterCallIterativelyDrainsOnStop(t *testing.T) {
+	sw := StopWaiter{}
+	sw.Start(context.Background(), &TestStruct{})
+	triggerChan := make(chan int, 10)
+	firstCallStarted := make(chan struct{})
+	var processed []int
+	err := CallIterativelyWithOptions(&sw, func(ctx context.Context, val int) time.Duration {
+		if val == 0 {
+			close(firstCallStarted)
+			<-ctx.Done()
+			return time.Hour
+		}
+		if ctx.Err() != nil {
+			t.Error("drained call got a cancelled context")
+		}
+		processed = append(processed, val)
+		return time.Hour
+	}, triggerChan, WithDrainOnStop())
+	testhelpers.RequireImpl(t, err)
+	<-firstCallStarted
+	for i := 1; i <= 3; i++ {
+		triggerChan <- i
+	}
+	sw.StopAndWait()
+	if len(processed) != 3 || processed[0] != 1 || processed[1] != 2 || processed[2] != 3 {
+		testhelpers.FailImpl(t, "expected the queued triggers to be processed in order on stop, got", processed)
+	}
+}
+
+type metricsTestStruct struct{}
+
+func TestStopWaiterComponentMetricsIndexedOnCollision(t *testing.T) {
+	const prefix = "arb/stopwaiter/component/stopwaiter_metricsTestStruct"
+	first := &StopWaiterSafe{}
+	testhelpers.RequireImpl(t, first.Start(context.Background(), &metricsTestStruct{}))
+	second := &StopWaiterSafe{}
+	testhelpers.RequireImpl(t, second.Start(context.Background(), &metricsTestStruct{}))
+	for _, name := range []string{prefix + "/threads/active", prefix + "_1/threads/active"} {
+		if metrics.Get(name) == nil {
+			t.Fatalf("expected %v to be registered", name)
+		}
+	}
+	testhelpers.RequireImpl(t, first.StopAndWait())
+	if metrics.Get(prefix+"/threads/active") != nil {
+		t.Fatal("expected metrics of a stopped StopWaiter to be unregistered")
+	}
+	// the freed index is reused by the next instance
+	third := &StopWaiterSafe{}
+	testhelpers.RequireImpl(t, third.Start(context.Background(), &metricsTestStruct{}))
+	if metrics.Get(prefix+"/threads/active") == nil {
+		t.Fatal("expected the freed label to be reused")
+	}
+	testhelpers.RequireImpl(t, second.StopAndWait())
+	testhelpers.RequireImpl(t, third.StopAndWait())
+	if metrics.Get(prefix+"_1/threads/active") != nil || metrics.Get(prefix+"/threads/active") != nil {
+		t.Fatal("expected all metrics to be unregistered once every instance stopped")
+	}
+}